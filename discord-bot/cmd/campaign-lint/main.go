@@ -0,0 +1,47 @@
+// Command campaign-lint checks campaign files for mistakes that would otherwise only show up during a session.
+//
+// Usage:
+//
+//	campaign-lint [-language de] <name>-campaign.yml...
+//
+// Every problem is printed like "file:line:column: severity: message". Exits with 1 if any file has errors.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/pnp"
+)
+
+func main() {
+	language := flag.String("language", "auto", "spoken language of the sessions to find names and aliases that are common words. 'auto' checks all known languages.")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <campaign file>...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		problems := pnp.Lint(data, *language)
+		for _, problem := range problems {
+			fmt.Printf("%s:%s\n", path, problem)
+		}
+		failed = failed || pnp.HasErrors(problems)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
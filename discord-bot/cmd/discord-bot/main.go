@@ -10,7 +10,9 @@ import (
 	"github.com/MrWong99/TaileVoices/discord_bot/pkg/audio"
 	"github.com/MrWong99/TaileVoices/discord_bot/pkg/bot"
 	"github.com/MrWong99/TaileVoices/discord_bot/pkg/config"
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
 	"github.com/MrWong99/TaileVoices/discord_bot/pkg/oai"
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/usage"
 	"github.com/MrWong99/TaileVoices/discord_bot/pkg/vecdb"
 	"github.com/bwmarrin/discordgo"
 )
@@ -34,6 +36,26 @@ func main() {
 	}
 	defer audio.UnloadSTTModel()
 	oai.Init(cfg.OpenAI.Token)
+	if cfg.ChatModel.BaseURL == "" {
+		token := cfg.ChatModel.Token
+		if token == "" {
+			token = cfg.OpenAI.Token
+		}
+		llm.SetDefaultModel(llm.NewOpenAI(token, cfg.ChatModel.Model))
+	} else {
+		chatModel, err := llm.NewOpenAICompatible(cfg.ChatModel.BaseURL, cfg.ChatModel.Token, cfg.ChatModel.Model)
+		if err != nil {
+			slog.ErrorContext(mainCtx, "could not setup chat model", "error", err)
+			os.Exit(1)
+		}
+		llm.SetDefaultModel(chatModel)
+	}
+	usage.SetDefaultBudget(usage.Budget{
+		Soft:       cfg.Budget.Soft,
+		Hard:       cfg.Budget.Hard,
+		CheapModel: cfg.Budget.CheapModel,
+		Prices:     cfg.Budget.Prices,
+	})
 	db, err := vecdb.NewClient(cfg.Weaviate.Scheme, cfg.Weaviate.Address)
 	if err != nil {
 		slog.ErrorContext(mainCtx, "could not setup vector db", "error", err)
@@ -60,8 +82,9 @@ func main() {
 	}
 
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGABRT)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGABRT)
 
 	sig := <-sigChan
 	slog.InfoContext(mainCtx, "received signal to shutdown", "signal", sig.String())
+	bot.Shutdown()
 }
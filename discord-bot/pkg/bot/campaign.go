@@ -2,20 +2,95 @@ package bot
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/MrWong99/TaileVoices/discord_bot/pkg/audio"
 	"github.com/MrWong99/TaileVoices/discord_bot/pkg/pnp"
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/usage"
 	"github.com/MrWong99/TaileVoices/discord_bot/pkg/uservoice"
 	"github.com/bwmarrin/discordgo"
+	"github.com/sashabaranov/go-openai"
+	"gopkg.in/hraban/opus.v2"
 )
 
+// journalInterval in which running sessions are written to their journal.
+const journalInterval = 30 * time.Second
+
+// interactionTokenLifetime is how long interaction responses can be edited with a safety margin. Discord invalidates
+// interaction tokens after 15 minutes.
+const interactionTokenLifetime = 12 * time.Minute
+
+// announcerVoice that speaks announcements like dice rolls.
+const announcerVoice = openai.VoiceFable
+
 var campaignCommand = discordgo.ApplicationCommand{
 	Name:        "campaign",
 	Description: "Join the voice channel and manage the campaign with given name.",
-	Options:     optionsByName("campaign", "language"),
+	Options:     optionsByName("campaign", "language", "resume"),
+}
+
+// Running campaigns by guild ID.
+var (
+	activeCampaigns   = make(map[string]*campaignSession)
+	activeCampaignsMu sync.Mutex
+)
+
+// campaignSession is a campaign that is running in a voice channel.
+type campaignSession struct {
+	campaign *pnp.Campaign
+	// file that the campaign was configured with.
+	file string
+	// fileMu guards writes of the bot to the file, so the watcher doesn't reload them.
+	fileMu sync.Mutex
+	// writtenModTime of the file after the last write of the bot.
+	writtenModTime time.Time
+	// announcements that will be spoken in the voice channel in between the actor responses.
+	announcements chan announcement
+}
+
+// announcement that is spoken in the voice channel of a session without belonging to an actor response.
+type announcement struct {
+	text  string
+	voice openai.SpeechVoice
+	// speaker that the speech usage is recorded for.
+	speaker string
+}
+
+// announce the text in the voice channel of the session. The announcement is dropped if too many are queued already.
+func (s *campaignSession) announce(text string) {
+	s.enqueue(announcement{text: text, voice: announcerVoice, speaker: pnp.UsageAnnouncer})
+}
+
+// narrate the text in the voice of the narrator of the campaign. Uses the announcer voice if there is no narrator.
+func (s *campaignSession) narrate(text string) {
+	s.enqueue(announcement{text: text, voice: narratorVoice(s.campaign), speaker: pnp.UsageNarrator})
+}
+
+// writeFile of the campaign with the write function that returns the modification time of the written file.
+// The watcher won't reload the campaign because of this write.
+func (s *campaignSession) writeFile(write func(path string) (time.Time, error)) error {
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+	modTime, err := write(s.file)
+	if err == nil {
+		s.writtenModTime = modTime
+	}
+	return err
+}
+
+func (s *campaignSession) enqueue(a announcement) {
+	select {
+	case s.announcements <- a:
+	default:
+		slog.Warn("dropping announcement as too many are queued", "campaign", s.campaign.Name, "text", a.text)
+	}
 }
 
 func campaignHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
@@ -32,15 +107,16 @@ func campaignHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		}
 		return
 	}
-	resolvedOptions := resolveAllOptions(data.Options, "campaign", "language")
+	resolvedOptions := resolveAllOptions(data.Options, "campaign", "language", "resume")
 
-	campaignData, err := os.ReadFile(resolvedOptions["campaign"].(string) + "-campaign.yml")
+	journalPath := resolvedOptions["campaign"].(string) + "-journal.yml"
+	campaign, msg, err := loadCampaign(resolvedOptions["campaign"].(string), journalPath, resolvedOptions["resume"].(bool))
 	if err != nil {
-		slog.Warn("could not find campaign data", "campaign", resolvedOptions["campaign"], "error", err)
+		slog.Warn("could not load campaign", "campaign", resolvedOptions["campaign"], "error", err)
 		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "No configuration found for this campaign",
+				Content: msg,
 			},
 		})
 		if err != nil {
@@ -48,19 +124,11 @@ func campaignHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		}
 		return
 	}
-	campaign, err := pnp.CampaignFromYaml(campaignData)
+	ledger, err := usage.OpenLedger(resolvedOptions["campaign"].(string) + "-usage.yml")
 	if err != nil {
-		slog.Warn("could not read campaign data", "campaign", resolvedOptions["campaign"], "error", err)
-		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Content: "No valid configuration found for this campaign",
-			},
-		})
-		if err != nil {
-			slog.Warn("could not create interaction response", "error", err)
-		}
-		return
+		slog.Warn("could not open usage ledger, usage of this session is not persisted", "campaign", campaign.Name, "error", err)
+	} else {
+		campaign.SetUsageLedger(ledger)
 	}
 	sttPrompt, err := campaign.STTPrompt()
 	if err != nil {
@@ -98,6 +166,14 @@ func campaignHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 							Label:    "STOP",
 							CustomID: "stop_campaign",
 						},
+						discordgo.Button{
+							Emoji: &discordgo.ComponentEmoji{
+								Name: "🤫",
+							},
+							Style:    discordgo.SecondaryButton,
+							Label:    "BREAK CHAIN",
+							CustomID: "break_chain",
+						},
 					},
 				},
 			},
@@ -140,18 +216,48 @@ func campaignHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		time.Sleep(50 * time.Millisecond)
 	}
 
-	go handleCampaignAudioOutput(campaign, voiceConn)
+	if campaign.CurrentNarrator() != nil {
+		recapSession(s, i.ChannelID, voiceConn, campaign)
+	}
+
+	session := &campaignSession{
+		campaign:      campaign,
+		file:          campaignFile(resolvedOptions["campaign"].(string)),
+		announcements: make(chan announcement, 8),
+	}
+	go handleCampaignAudioOutput(session, voiceConn)
+	campaign.StartJournal(journalPath, journalInterval)
+	if len(campaign.Lore) > 0 {
+		go func() {
+			if err := campaign.IngestLore(); err != nil {
+				slog.Warn("could not ingest lore", "campaign", campaign.Name, "error", err)
+			}
+		}()
+	}
+	registerCampaign(i.GuildID, session)
+	defer unregisterCampaign(i.GuildID)
+	stopWatching := make(chan struct{})
+	defer close(stopWatching)
+	go watchCampaignFile(s, i.ChannelID, session, stopWatching)
 
 	if _, ok := componentButtons[i.GuildID]; !ok {
 		componentButtons[i.GuildID] = make(map[string]chan *discordgo.Interaction)
 	}
 	componentButtons[i.GuildID]["stop_campaign"] = make(chan *discordgo.Interaction)
+	componentButtons[i.GuildID]["break_chain"] = make(chan *discordgo.Interaction)
+	defer func() {
+		close(componentButtons[i.GuildID]["break_chain"])
+		delete(componentButtons[i.GuildID], "break_chain")
+	}()
 
 	voices := make(map[uint32]*uservoice.Voice)
-	userIDs := make(map[uint32]string)
+	usernames := make(map[uint32]string)
+	discordIDs := make(map[uint32]string)
+	// speakingSince is the start of the speech of each user without a pause.
+	speakingSince := make(map[uint32]time.Time)
 
 	s.VoiceConnections[voiceConn.GuildID].AddHandler(func(_ *discordgo.VoiceConnection, vs *discordgo.VoiceSpeakingUpdate) {
-		_, ok := userIDs[uint32(vs.SSRC)]
+		_, ok := usernames[uint32(vs.SSRC)]
 		if ok {
 			return
 		}
@@ -163,17 +269,17 @@ func campaignHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		} else {
 			name = user.Username
 		}
-		mappedName, ok := campaign.PlayerName(name)
-		if !ok {
-			slog.Warn("user speaking that has no name mapping", "campaign", campaign.Name, "user", name)
-			mappedName = name
+		if _, ok := campaign.PlayerName(vs.UserID, name); !ok {
+			slog.Warn("user speaking that is not registered as player", "campaign", campaign.Name, "userID", vs.UserID, "user", name)
 		}
-		userIDs[uint32(vs.SSRC)] = mappedName
+		usernames[uint32(vs.SSRC)] = name
+		discordIDs[uint32(vs.SSRC)] = vs.UserID
 		voice, ok := voices[uint32(vs.SSRC)]
 		if !ok {
 			return
 		}
-		voice.Username = mappedName
+		voice.Username = name
+		voice.UserID = vs.UserID
 	})
 
 	for {
@@ -181,18 +287,13 @@ func campaignHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		var ok bool
 		select {
 		case respI := <-componentButtons[i.GuildID]["stop_campaign"]:
+			stoppedAt := time.Now()
 			defer func() {
-				if err := campaign.Close(); err != nil {
-					slog.Warn("unexpected error while stopping campaign", "campaign", campaign.Name, "error", err)
-				}
-				summary, err := campaign.Summary()
-				if err != nil {
-					slog.Warn("could not summarize campaign transcript", "error", err)
-				} else {
-					s.InteractionResponseEdit(respI, &discordgo.WebhookEdit{
-						Content: &summary,
-					})
+				// Stop the actors right away, so the voice connection can be closed while the transcript is finished.
+				if err := campaign.Suspend(); err != nil {
+					slog.Warn("could not suspend campaign", "campaign", campaign.Name, "error", err)
 				}
+				go finishCampaign(s, respI, stoppedAt, campaign)
 				// Cleanup
 				close(componentButtons[i.GuildID]["stop_campaign"])
 				delete(componentButtons[i.GuildID], "stop_campaign")
@@ -206,54 +307,261 @@ func campaignHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 						{
 							Name:        "transcript.txt",
 							ContentType: "text/plain",
-							Reader:      bytes.NewReader([]byte(campaign.CurrentSessionTranscript)),
+							Reader:      bytes.NewReader([]byte(campaign.CurrentTranscript())),
 						},
 					},
 				},
 			})
 			return
+		case respI := <-componentButtons[i.GuildID]["break_chain"]:
+			if campaign.BreakChain() {
+				slog.Info("GM broke the exchange between actors", "campaign", campaign.Name)
+			}
+			err := s.InteractionRespond(respI, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseDeferredMessageUpdate,
+			})
+			if err != nil {
+				slog.Warn("could not create interaction response", "error", err)
+			}
+			continue
 		case p, ok = <-voiceConn.OpusRecv:
 			if !ok {
+				if err := campaign.Suspend(); err != nil {
+					slog.Warn("could not suspend campaign", "campaign", campaign.Name, "error", err)
+				}
 				return
 			}
 		}
 		voice, ok := voices[p.SSRC]
 		if !ok {
-			uid := userIDs[p.SSRC]
-			name, ok := campaign.PlayerName(uid)
-			if !ok {
-				slog.Warn("user speaking that has no name mapping", "campaign", campaign.Name, "userID", uid)
-				name = uid
-			}
-			voice, err = uservoice.NewVoice(name, p.SSRC, stt)
+			voice, err = uservoice.NewVoice(usernames[p.SSRC], p.SSRC, stt)
 			if err != nil {
 				slog.Error("could not create voice receiver", "SSRC", p.SSRC, "error", err)
 				continue
 			}
+			voice.UserID = discordIDs[p.SSRC]
 			voices[p.SSRC] = voice
 			defer voice.Close()
 			go handleCampaignAudioInput(voice, campaign)
 		}
+		if isSilenceFrame(p.Opus) {
+			delete(speakingSince, p.SSRC)
+		} else {
+			since, ok := speakingSince[p.SSRC]
+			if !ok {
+				since = time.Now()
+				speakingSince[p.SSRC] = since
+			}
+			campaign.PlayerSpeaking(voice.UserID, time.Since(since))
+		}
 		if err := voice.Process(p.Opus); err != nil {
 			slog.Error("could not process audio data", "SSRC", p.SSRC, "error", err)
 		}
 	}
 }
 
+// finishCampaign lets the GM review the correction of the transcript if enabled, stores the transcript and posts the summary
+// of the session. The summary replaces the response to the interaction that stopped the campaign if its token is still valid
+// and is sent to the channel otherwise.
+func finishCampaign(s *discordgo.Session, i *discordgo.Interaction, stoppedAt time.Time, campaign *pnp.Campaign) {
+	if campaign.Correction.Enabled {
+		reviewCorrection(s, i.ChannelID, i.GuildID, campaign)
+	}
+	if err := campaign.Close(); err != nil {
+		slog.Warn("unexpected error while stopping campaign", "campaign", campaign.Name, "error", err)
+	}
+	summary, err := campaign.Summary()
+	if err != nil {
+		slog.Warn("could not summarize campaign transcript", "error", err)
+		return
+	}
+	embed, shortened := summaryEmbed(summary)
+	// Files added by an edit would replace the transcript attached to the stop message, so shortened summaries are posted separately.
+	if !shortened && time.Since(stoppedAt) < interactionTokenLifetime {
+		_, err = s.InteractionResponseEdit(i, &discordgo.WebhookEdit{
+			Embeds: &[]*discordgo.MessageEmbed{embed},
+		})
+		if err == nil {
+			return
+		}
+		slog.Warn("could not add session summary to the stop message, posting it instead", "campaign", campaign.Name, "error", err)
+	}
+	msg := &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{embed},
+	}
+	if shortened {
+		msg.Files = []*discordgo.File{
+			{
+				Name:        "summary.md",
+				ContentType: "text/markdown",
+				Reader:      bytes.NewReader([]byte(summary.String())),
+			},
+		}
+	}
+	if _, err := s.ChannelMessageSendComplex(i.ChannelID, msg); err != nil {
+		slog.Warn("could not post session summary", "campaign", campaign.Name, "error", err)
+	}
+}
+
+// loadCampaign from its configuration file or from its journal if the last session should be resumed.
+// Returns a message for the user if it fails.
+func loadCampaign(name, journalPath string, resume bool) (*pnp.Campaign, string, error) {
+	if resume {
+		campaign, err := pnp.ResumeCampaign(journalPath)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, "There is no unfinished session of this campaign to resume", err
+		}
+		if err != nil {
+			return nil, "The unfinished session of this campaign could not be restored", err
+		}
+		return campaign, "", nil
+	}
+	if _, err := os.Stat(journalPath); err == nil {
+		return nil, "There is an unfinished session of this campaign. Use the resume option to continue it.", fmt.Errorf("journal %s already exists", journalPath)
+	}
+	campaignData, err := os.ReadFile(campaignFile(name))
+	if err != nil {
+		return nil, "No configuration found for this campaign", err
+	}
+	campaign, err := pnp.CampaignFromYaml(campaignData)
+	if err != nil {
+		return nil, "No valid configuration found for this campaign", err
+	}
+	return campaign, "", nil
+}
+
+// campaignFile that configures the campaign with the given name.
+func campaignFile(name string) string {
+	return name + "-campaign.yml"
+}
+
+func registerCampaign(guildID string, session *campaignSession) {
+	activeCampaignsMu.Lock()
+	defer activeCampaignsMu.Unlock()
+	activeCampaigns[guildID] = session
+}
+
+// activeCampaign that is running in the guild.
+func activeCampaign(guildID string) (*campaignSession, bool) {
+	activeCampaignsMu.Lock()
+	defer activeCampaignsMu.Unlock()
+	session, ok := activeCampaigns[guildID]
+	return session, ok
+}
+
+func unregisterCampaign(guildID string) {
+	activeCampaignsMu.Lock()
+	defer activeCampaignsMu.Unlock()
+	delete(activeCampaigns, guildID)
+}
+
+// Shutdown suspends all running campaign sessions and writes their journals, so they can be resumed after a restart.
+func Shutdown() {
+	activeCampaignsMu.Lock()
+	defer activeCampaignsMu.Unlock()
+	for guildID, session := range activeCampaigns {
+		if err := session.campaign.Suspend(); err != nil {
+			slog.Error("could not suspend campaign", "guildID", guildID, "campaign", session.campaign.Name, "error", err)
+			continue
+		}
+		slog.Info("suspended campaign", "guildID", guildID, "campaign", session.campaign.Name)
+	}
+}
+
+// handleCampaignAudioInput adds the transcribed speech of the user to the campaign under the name of the character
+// that the user currently plays. Users that are not registered as player keep their username.
 func handleCampaignAudioInput(voice *uservoice.Voice, campaign *pnp.Campaign) {
 	for segment := range voice.C() {
-		campaign.HandleText(voice.Username, segment.Text)
+		name, ok := campaign.PlayerName(voice.UserID, voice.Username)
+		if !ok {
+			name = voice.Username
+		}
+		campaign.HandleText(pnp.TranscriptEntry{
+			SpeakerID: voice.UserID,
+			Name:      name,
+			Start:     voice.StartTime().Add(segment.Start),
+			End:       voice.StartTime().Add(segment.End),
+			Source:    pnp.SourceSTT,
+			Text:      segment.Text,
+		})
 	}
 }
 
-func handleCampaignAudioOutput(campaign *pnp.Campaign, voiceConn *discordgo.VoiceConnection) {
-	for response := range campaign.C() {
-		o, err := createAudioResponse(response.Text, response.Actor.Voice)
-		if err != nil {
-			slog.Error("failed to create audio response", "error", err)
-			return
+// speechChunk is a sentence of an actor response that is queued for playback. A chunk without audio finishes the response.
+// Announcements don't belong to any response.
+type speechChunk struct {
+	response     pnp.ActorResponse
+	sentence     string
+	audio        *opus.Stream
+	announcement bool
+}
+
+// handleCampaignAudioOutput converts each sentence of the actor responses to speech as soon as it arrives.
+// Playback happens in its own goroutine so the next sentence can already be converted while the previous one is spoken.
+// Interrupted responses stop mid-sentence and report how much has been spoken. Announcements are spoken in between the responses.
+func handleCampaignAudioOutput(session *campaignSession, voiceConn *discordgo.VoiceConnection) {
+	playback := make(chan speechChunk, 8)
+	defer close(playback)
+	go func() {
+		for chunk := range playback {
+			if chunk.announcement {
+				speakAudio(context.Background(), voiceConn, chunk.audio)
+				chunk.audio.Close()
+				continue
+			}
+			if chunk.audio == nil {
+				chunk.response.Finish()
+				continue
+			}
+			chunk.response.PlaybackStarted()
+			played, complete := speakAudio(chunk.response.Context(), voiceConn, chunk.audio)
+			chunk.audio.Close()
+			if complete {
+				chunk.response.Spoken(chunk.sentence)
+			} else {
+				chunk.response.SpokenPartially(chunk.sentence, played)
+			}
 		}
+	}()
 
-		speakAudio(voiceConn, o)
+	responses := session.campaign.C()
+	for {
+		var response pnp.ActorResponse
+		select {
+		case a := <-session.announcements:
+			o, err := createAudioResponse(context.Background(), a.text, a.voice)
+			if err != nil {
+				slog.Error("failed to create audio for announcement", "campaign", session.campaign.Name, "error", err)
+				continue
+			}
+			session.campaign.RecordSpeech(a.speaker, a.text)
+			playback <- speechChunk{sentence: a.text, audio: o, announcement: true}
+			continue
+		case r, ok := <-responses:
+			if !ok {
+				return
+			}
+			response = r
+		}
+		for sentence := range response.Chunks {
+			if response.Context().Err() != nil {
+				continue // Interrupted, just drain the remaining chunks
+			}
+			o, err := createAudioResponse(response.Context(), sentence, response.Actor.Voice)
+			if err != nil {
+				if response.Context().Err() == nil {
+					slog.Error("failed to create audio response", "actor", response.Actor.Name, "error", err)
+				}
+				continue
+			}
+			session.campaign.RecordSpeech(response.Actor.Name, sentence)
+			playback <- speechChunk{response: response, sentence: sentence, audio: o}
+		}
+		playback <- speechChunk{response: response}
 	}
 }
+
+// isSilenceFrame returns true for the opus frames that Discord sends after a user stopped speaking.
+func isSilenceFrame(data []byte) bool {
+	return bytes.Equal(data, []byte{0xF8, 0xFF, 0xFE})
+}
@@ -10,13 +10,23 @@ import (
 // Mapping goes Interaction.GuildID -> Component.CustomID
 var componentButtons = make(map[string]map[string]chan *discordgo.Interaction)
 
-var commands = []*discordgo.ApplicationCommand{&sayCommand, &transcribeCommand, &recordRawCommand, &campaignCommand}
+var commands = []*discordgo.ApplicationCommand{&sayCommand, &transcribeCommand, &recordRawCommand, &campaignCommand, &rollCommand, &usageCommand, &npcCommand, &recapCommand, &playerCommand}
 
 var handlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
 	sayCommand.Name:        sayHandler,
 	transcribeCommand.Name: transcribeHandler,
 	recordRawCommand.Name:  recordRawHandler,
 	campaignCommand.Name:   campaignHandler,
+	rollCommand.Name:       rollHandler,
+	usageCommand.Name:      usageHandler,
+	npcCommand.Name:        npcHandler,
+	recapCommand.Name:      recapHandler,
+	playerCommand.Name:     playerHandler,
+}
+
+// autocompleteHandlers suggest values for the options of commands while they are typed.
+var autocompleteHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+	npcCommand.Name: npcAutocomplete,
 }
 
 // SetupCommands that the session will respond to.
@@ -35,6 +45,12 @@ func SetupCommands(session *discordgo.Session) error {
 			}
 			return
 		}
+		if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+			if h, ok := autocompleteHandlers[i.ApplicationCommandData().Name]; ok {
+				h(s, i)
+			}
+			return
+		}
 		if i.Type != discordgo.InteractionApplicationCommand {
 			return
 		}
@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/pnp"
+	"github.com/bwmarrin/discordgo"
+)
+
+// correctionReviewTimeout after which the original transcript is stored if the GM didn't review the correction.
+const correctionReviewTimeout = 10 * time.Minute
+
+// reviewCorrection lets a LLM correct the transcript of the campaign and asks the GM in a message to the channel whether
+// the corrected transcript should be stored. The original transcript is kept if the GM rejects the correction, doesn't
+// answer in time or the correction fails.
+func reviewCorrection(s *discordgo.Session, channelID, guildID string, campaign *pnp.Campaign) {
+	correction, err := campaign.CorrectTranscript()
+	if err != nil {
+		slog.Warn("could not correct transcript", "campaign", campaign.Name, "error", err)
+		return
+	}
+	if !correction.Changed() {
+		slog.Info("transcript correction changed nothing", "campaign", campaign.Name)
+		return
+	}
+
+	if _, ok := componentButtons[guildID]; !ok {
+		componentButtons[guildID] = make(map[string]chan *discordgo.Interaction)
+	}
+	componentButtons[guildID]["accept_correction"] = make(chan *discordgo.Interaction)
+	componentButtons[guildID]["reject_correction"] = make(chan *discordgo.Interaction)
+	defer func() {
+		for _, id := range []string{"accept_correction", "reject_correction"} {
+			close(componentButtons[guildID][id])
+			delete(componentButtons[guildID], id)
+		}
+	}()
+	msg, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: fmt.Sprintf("Please review the corrections of the transcript before it is stored. The original transcript is stored if you don't accept them within %s.", correctionReviewTimeout),
+		Files: []*discordgo.File{
+			{
+				Name:        "corrections.diff",
+				ContentType: "text/plain",
+				Reader:      bytes.NewReader([]byte(correction.Diff())),
+			},
+			{
+				Name:        "corrected-transcript.txt",
+				ContentType: "text/plain",
+				Reader:      bytes.NewReader([]byte(correction.Corrected)),
+			},
+		},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Emoji: &discordgo.ComponentEmoji{
+							Name: "✅",
+						},
+						Style:    discordgo.SuccessButton,
+						Label:    "STORE CORRECTED",
+						CustomID: "accept_correction",
+					},
+					discordgo.Button{
+						Emoji: &discordgo.ComponentEmoji{
+							Name: "↩️",
+						},
+						Style:    discordgo.SecondaryButton,
+						Label:    "STORE ORIGINAL",
+						CustomID: "reject_correction",
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		slog.Warn("could not ask for review of the transcript correction", "campaign", campaign.Name, "error", err)
+		return
+	}
+
+	var content string
+	var respI *discordgo.Interaction
+	select {
+	case respI = <-componentButtons[guildID]["accept_correction"]:
+		campaign.AcceptCorrection(correction)
+		content = "The corrected transcript will be stored."
+	case respI = <-componentButtons[guildID]["reject_correction"]:
+		content = "The original transcript will be stored."
+	case <-time.After(correctionReviewTimeout):
+		content = "The corrections were not reviewed in time, so the original transcript will be stored."
+	}
+	slog.Info("transcript correction reviewed", "campaign", campaign.Name, "result", content)
+	if respI != nil {
+		err = s.InteractionRespond(respI, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    content,
+				Components: []discordgo.MessageComponent{},
+			},
+		})
+	} else {
+		_, err = s.ChannelMessageEditComplex(&discordgo.MessageEdit{
+			ID:         msg.ID,
+			Channel:    channelID,
+			Content:    &content,
+			Components: &[]discordgo.MessageComponent{},
+		})
+	}
+	if err != nil {
+		slog.Warn("could not create interaction response", "error", err)
+	}
+}
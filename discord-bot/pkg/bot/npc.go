@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxAutocompleteChoices that Discord accepts in a single autocomplete response.
+const maxAutocompleteChoices = 25
+
+var npcCommand = discordgo.ApplicationCommand{
+	Name:        "npc",
+	Description: "Control the actors of the campaign that is running in this server.",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "mute",
+			Description: "Prevent an actor from responding until it is unmuted.",
+			Options:     optionsByName("actor"),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "unmute",
+			Description: "Let a muted actor respond again.",
+			Options:     optionsByName("actor"),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "speak",
+			Description: "Make an actor respond right now, even if it is muted.",
+			Options:     optionsByName("actor", "hint"),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "line",
+			Description: "Make an actor say exactly the given text in its voice.",
+			Options:     optionsByName("actor", "text"),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "List the actors of the running campaign.",
+		},
+	},
+}
+
+func npcHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	session, ok := activeCampaign(i.GuildID)
+	if !ok {
+		respondEphemeral(s, i, "There is no campaign running in this server.")
+		return
+	}
+	campaign := session.campaign
+	subcommand := i.ApplicationCommandData().Options[0]
+	resolvedOptions := resolveAllOptions(subcommand.Options, "actor", "hint", "text")
+
+	switch subcommand.Name {
+	case "mute", "unmute":
+		muted := subcommand.Name == "mute"
+		name, err := campaign.SetMuted(resolvedOptions["actor"].(string), muted)
+		if err != nil {
+			respondEphemeral(s, i, fmt.Sprintf("Could not %s actor: %v", subcommand.Name, err))
+			return
+		}
+		if muted {
+			respondEphemeral(s, i, fmt.Sprintf("**%s** is muted and will only speak when told to.", name))
+		} else {
+			respondEphemeral(s, i, fmt.Sprintf("**%s** may respond again.", name))
+		}
+	case "speak":
+		name, err := campaign.Speak(resolvedOptions["actor"].(string), resolvedOptions["hint"].(string))
+		if err != nil {
+			respondEphemeral(s, i, fmt.Sprintf("Could not make actor speak: %v", err))
+			return
+		}
+		respondEphemeral(s, i, fmt.Sprintf("**%s** is about to speak.", name))
+	case "line":
+		name, err := campaign.Line(resolvedOptions["actor"].(string), resolvedOptions["text"].(string))
+		if err != nil {
+			respondEphemeral(s, i, fmt.Sprintf("Could not queue line: %v", err))
+			return
+		}
+		respondEphemeral(s, i, fmt.Sprintf("**%s** is about to say it.", name))
+	case "list":
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Actors of **%s**:\n", campaign.Name)
+		for _, actor := range campaign.CurrentActors() {
+			status := "active"
+			if campaign.IsMuted(actor.Name) {
+				status = "muted"
+			}
+			fmt.Fprintf(&sb, "- **%s** (%s): %s\n", actor.Name, actor.Voice, status)
+		}
+		respondEphemeral(s, i, sb.String())
+	default:
+		slog.Warn("unknown npc subcommand", "subcommand", subcommand.Name)
+	}
+}
+
+// npcAutocomplete suggests the names of the actors in the running campaign that contain what has been typed so far.
+func npcAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+	if session, ok := activeCampaign(i.GuildID); ok {
+		typed := ""
+		for _, option := range i.ApplicationCommandData().Options[0].Options {
+			if option.Focused {
+				typed = strings.ToLower(option.StringValue())
+			}
+		}
+		for _, actor := range session.campaign.CurrentActors() {
+			if len(choices) == maxAutocompleteChoices {
+				break
+			}
+			if strings.Contains(strings.ToLower(actor.Name), typed) {
+				choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: actor.Name, Value: actor.Name})
+			}
+		}
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+	if err != nil {
+		slog.Warn("could not create autocomplete response", "error", err)
+	}
+}
+
+// respondEphemeral with content that only the user who ran the command can see.
+func respondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, content string) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		slog.Warn("could not create interaction response", "error", err)
+	}
+}
@@ -115,6 +115,121 @@ var commandOptions = map[string]advancedCommandOption{
 			return make(map[string]any)
 		},
 	},
+	"resume": {
+		option: &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "resume",
+			Description: "Continue the last session of this campaign if it was not stopped properly.",
+			Required:    false,
+		},
+		resolver: func(options []*discordgo.ApplicationCommandInteractionDataOption) map[string]any {
+			for _, option := range options {
+				if option.Name == "resume" {
+					return map[string]any{
+						"resume": option.BoolValue(),
+					}
+				}
+			}
+			return map[string]any{
+				"resume": false,
+			}
+		},
+	},
+	"dice": {
+		option: &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "dice",
+			Description: "The dice to roll like 2d6+3, 4d6kh3, d20 adv, 3d6! or 4dF",
+			Required:    true,
+		},
+		resolver: func(options []*discordgo.ApplicationCommandInteractionDataOption) map[string]any {
+			for _, option := range options {
+				if option.Name == "dice" {
+					return map[string]any{
+						"dice": option.StringValue(),
+					}
+				}
+			}
+			return make(map[string]any)
+		},
+	},
+	"announce": {
+		option: &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "announce",
+			Description: "Announce the result in the voice channel of the running campaign.",
+			Required:    false,
+		},
+		resolver: func(options []*discordgo.ApplicationCommandInteractionDataOption) map[string]any {
+			for _, option := range options {
+				if option.Name == "announce" {
+					return map[string]any{
+						"announce": option.BoolValue(),
+					}
+				}
+			}
+			return map[string]any{
+				"announce": false,
+			}
+		},
+	},
+	"actor": {
+		option: &discordgo.ApplicationCommandOption{
+			Type:         discordgo.ApplicationCommandOptionString,
+			Name:         "actor",
+			Description:  "The name of an actor in the running campaign.",
+			Required:     true,
+			Autocomplete: true,
+		},
+		resolver: func(options []*discordgo.ApplicationCommandInteractionDataOption) map[string]any {
+			for _, option := range options {
+				if option.Name == "actor" {
+					return map[string]any{
+						"actor": option.StringValue(),
+					}
+				}
+			}
+			return make(map[string]any)
+		},
+	},
+	"character": {
+		option: &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "character",
+			Description: "The ingame name of your character.",
+			Required:    true,
+		},
+		resolver: func(options []*discordgo.ApplicationCommandInteractionDataOption) map[string]any {
+			for _, option := range options {
+				if option.Name == "character" {
+					return map[string]any{
+						"character": option.StringValue(),
+					}
+				}
+			}
+			return make(map[string]any)
+		},
+	},
+	"hint": {
+		option: &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "hint",
+			Description: "A direction for the actor like what to talk about or how to behave.",
+			Required:    false,
+		},
+		resolver: func(options []*discordgo.ApplicationCommandInteractionDataOption) map[string]any {
+			for _, option := range options {
+				if option.Name == "hint" {
+					return map[string]any{
+						"hint": option.StringValue(),
+					}
+				}
+			}
+			return map[string]any{
+				"hint": "",
+			}
+		},
+	},
 }
 
 func optionsByName(names ...string) []*discordgo.ApplicationCommandOption {
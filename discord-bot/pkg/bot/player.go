@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/pnp"
+	"github.com/bwmarrin/discordgo"
+)
+
+var playerCommand = discordgo.ApplicationCommand{
+	Name:        "player",
+	Description: "Manage which character you play in the campaign that is running in this server.",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "register",
+			Description: "Register the character that you play in this campaign, also for the next sessions.",
+			Options:     optionsByName("character"),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "switch",
+			Description: "Play another character for this session only.",
+			Options:     optionsByName("character"),
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        "list",
+			Description: "List the players of the running campaign and their characters.",
+		},
+	},
+}
+
+func playerHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	session, ok := activeCampaign(i.GuildID)
+	if !ok {
+		respondEphemeral(s, i, "There is no campaign running in this server.")
+		return
+	}
+	campaign := session.campaign
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	subcommand := i.ApplicationCommandData().Options[0]
+	resolvedOptions := resolveAllOptions(subcommand.Options, "character")
+
+	switch subcommand.Name {
+	case "register":
+		character := resolvedOptions["character"].(string)
+		if err := campaign.RegisterPlayer(user.ID, user.Username, character); err != nil {
+			respondEphemeral(s, i, fmt.Sprintf("Could not register you: %v", err))
+			return
+		}
+		err := session.writeFile(func(path string) (time.Time, error) {
+			return pnp.RegisterPlayerInFile(path, user.ID, user.Username, character)
+		})
+		if err != nil {
+			slog.Warn("could not persist player registration", "campaign", campaign.Name, "file", session.file, "error", err)
+			respondEphemeral(s, i, fmt.Sprintf("You play **%s** in this session, but it could not be saved for the next ones: %v", strings.TrimSpace(character), err))
+			return
+		}
+		respondEphemeral(s, i, fmt.Sprintf("You play **%s** in **%s**.", strings.TrimSpace(character), campaign.Name))
+	case "switch":
+		if err := campaign.SwitchCharacter(user.ID, resolvedOptions["character"].(string)); err != nil {
+			respondEphemeral(s, i, fmt.Sprintf("Could not switch your character: %v", err))
+			return
+		}
+		name, _ := campaign.PlayerName(user.ID, user.Username)
+		respondEphemeral(s, i, fmt.Sprintf("You play **%s** for the rest of this session.", name))
+	case "list":
+		respondEphemeral(s, i, formatPlayers(campaign))
+	default:
+		slog.Warn("unknown player subcommand", "subcommand", subcommand.Name)
+	}
+}
+
+// formatPlayers of the campaign sorted by their characters.
+func formatPlayers(campaign *pnp.Campaign) string {
+	players := campaign.CurrentPlayers()
+	if len(players) == 0 {
+		return fmt.Sprintf("Nobody is registered in **%s** yet. Use /player register to do so.", campaign.Name)
+	}
+	userIDs := make([]string, 0, len(players))
+	for userID := range players {
+		userIDs = append(userIDs, userID)
+	}
+	slices.SortFunc(userIDs, func(a, b string) int { return strings.Compare(players[a], players[b]) })
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Players of **%s**:\n", campaign.Name)
+	for _, userID := range userIDs {
+		player := "<@" + userID + ">"
+		if !pnp.IsDiscordID(userID) {
+			player = userID + " (by username, please /player register)"
+		}
+		registered, ok := campaign.RegisteredCharacter(userID)
+		if ok && registered != players[userID] {
+			fmt.Fprintf(&sb, "- **%s** (this session, usually %s): %s\n", players[userID], registered, player)
+		} else {
+			fmt.Fprintf(&sb, "- **%s**: %s\n", players[userID], player)
+		}
+	}
+	return sb.String()
+}
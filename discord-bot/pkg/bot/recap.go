@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/pnp"
+	"github.com/bwmarrin/discordgo"
+	"github.com/sashabaranov/go-openai"
+)
+
+var recapCommand = discordgo.ApplicationCommand{
+	Name:        "recap",
+	Description: "Let the narrator recap the story of the running campaign.",
+}
+
+func recapHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	session, ok := activeCampaign(i.GuildID)
+	if !ok {
+		respondEphemeral(s, i, "There is no campaign running. Start one with /campaign first.")
+		return
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+	})
+	if err != nil {
+		slog.Warn("could not create interaction response", "error", err)
+		return
+	}
+
+	msg, err := session.campaign.Recap()
+	if err != nil {
+		slog.Warn("could not recap campaign", "campaign", session.campaign.Name, "error", err)
+		msg = fmt.Sprintf("Could not recap the story: %v", err)
+	} else {
+		session.narrate(msg)
+	}
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &msg}); err != nil {
+		slog.Warn("could not edit interaction response", "error", err)
+	}
+}
+
+// recapSession lets the narrator recap the story in the voice channel before the session starts. Returns once the recap
+// has been spoken. Audio of the players is dropped meanwhile, so the session only starts listening afterwards.
+func recapSession(s *discordgo.Session, channelID string, voiceConn *discordgo.VoiceConnection, campaign *pnp.Campaign) {
+	recap, err := campaign.Recap()
+	if err != nil {
+		slog.Info("no recap at session start", "campaign", campaign.Name, "error", err)
+		return
+	}
+	if _, err := s.ChannelMessageSend(channelID, fmt.Sprintf("*Previously on %s...*\n%s", campaign.Name, recap)); err != nil {
+		slog.Warn("could not send recap", "campaign", campaign.Name, "error", err)
+	}
+	o, err := createAudioResponse(context.Background(), recap, narratorVoice(campaign))
+	if err != nil {
+		slog.Error("failed to create audio for recap", "campaign", campaign.Name, "error", err)
+		return
+	}
+	defer o.Close()
+
+	spoken := make(chan struct{})
+	defer close(spoken)
+	go func() {
+		for {
+			select {
+			case _, ok := <-voiceConn.OpusRecv:
+				if !ok {
+					return
+				}
+			case <-spoken:
+				return
+			}
+		}
+	}()
+	campaign.RecordSpeech(pnp.UsageNarrator, recap)
+	speakAudio(context.Background(), voiceConn, o)
+}
+
+// narratorVoice of the campaign or the announcer voice if it has no narrator.
+func narratorVoice(campaign *pnp.Campaign) openai.SpeechVoice {
+	if narrator := campaign.CurrentNarrator(); narrator != nil && narrator.Voice != "" {
+		return narrator.Voice
+	}
+	return announcerVoice
+}
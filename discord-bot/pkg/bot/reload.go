@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// reloadInterval in which the configuration file of a running campaign is checked for changes.
+const reloadInterval = 5 * time.Second
+
+// watchCampaignFile of the session and reload the actors and players of the running campaign whenever it changes.
+// Changes that the bot wrote itself are skipped. The outcome of every reload is reported in the text channel.
+// Stops once done is closed.
+func watchCampaignFile(s *discordgo.Session, channelID string, session *campaignSession, done <-chan struct{}) {
+	campaign, path := session.campaign, session.file
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		session.fileMu.Lock()
+		info, err := os.Stat(path)
+		written := session.writtenModTime
+		session.fileMu.Unlock()
+		if err != nil || !info.ModTime().After(modTime) {
+			continue
+		}
+		modTime = info.ModTime()
+		if modTime.Equal(written) {
+			continue // Written by the bot itself, the campaign is already up to date
+		}
+
+		msg := fmt.Sprintf("Reloaded the actors and players of **%s**.", campaign.Name)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = campaign.Reload(data)
+		}
+		if err != nil {
+			slog.Warn("could not reload campaign", "campaign", campaign.Name, "path", path, "error", err)
+			msg = fmt.Sprintf("Could not reload **%s**, the session continues unchanged:\n```\n%v\n```", campaign.Name, err)
+		}
+		if _, err := s.ChannelMessageSend(channelID, msg); err != nil {
+			slog.Warn("could not send reload message", "campaign", campaign.Name, "error", err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/dice"
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/pnp"
+	"github.com/bwmarrin/discordgo"
+)
+
+var rollCommand = discordgo.ApplicationCommand{
+	Name:        "roll",
+	Description: "Roll dice. While a campaign is running the result becomes part of its transcript.",
+	Options:     optionsByName("dice", "announce"),
+}
+
+// roller for dice rolls outside of running campaigns.
+var roller = dice.NewRoller(time.Now().UnixNano())
+
+func rollHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	resolvedOptions := resolveAllOptions(data.Options, "dice", "announce")
+	notation := resolvedOptions["dice"].(string)
+
+	user := i.User
+	if i.Member != nil {
+		user = i.Member.User
+	}
+	name := user.Username
+	session, running := activeCampaign(i.GuildID)
+	if running {
+		if playerName, ok := session.campaign.PlayerName(user.ID, user.Username); ok {
+			name = playerName
+		}
+	}
+
+	var res dice.Result
+	var err error
+	if running {
+		res, err = session.campaign.Roll(notation)
+	} else {
+		res, err = roller.Roll(notation)
+	}
+	if err != nil {
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("I can't roll that: %v", err),
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+		if err != nil {
+			slog.Warn("could not create interaction response", "error", err)
+		}
+		return
+	}
+
+	content := fmt.Sprintf("**%s** rolled %s", name, res)
+	if running {
+		now := time.Now()
+		session.campaign.HandleText(pnp.TranscriptEntry{
+			SpeakerID: user.ID,
+			Name:      name,
+			Start:     now,
+			End:       now,
+			Source:    pnp.SourceDice,
+			Text:      "rolled " + res.String(),
+		})
+		if resolvedOptions["announce"].(bool) {
+			session.announce(fmt.Sprintf("%s rolled %d", name, res.Total))
+		}
+	} else if resolvedOptions["announce"].(bool) {
+		content += "\n-# Rolls can only be announced while a campaign is running."
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+		},
+	})
+	if err != nil {
+		slog.Warn("could not create interaction response", "error", err)
+	}
+}
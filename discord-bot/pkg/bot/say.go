@@ -44,7 +44,7 @@ func sayHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	}
 	resolvedOptions := resolveAllOptions(data.Options, "text", "voice")
 
-	opusInput, err := createAudioResponse(resolvedOptions["text"].(string), resolvedOptions["voice"].(openai.SpeechVoice))
+	opusInput, err := createAudioResponse(context.Background(), resolvedOptions["text"].(string), resolvedOptions["voice"].(openai.SpeechVoice))
 	if err != nil {
 		slog.Error("could not stream opus response from OpenAI", "error", err)
 		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
@@ -96,12 +96,12 @@ func sayHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		time.Sleep(50 * time.Millisecond)
 	}
 
-	speakAudio(voiceConn, opusInput)
+	speakAudio(context.Background(), voiceConn, opusInput)
 }
 
-func createAudioResponse(text string, voice openai.SpeechVoice) (*opus.Stream, error) {
+func createAudioResponse(ctx context.Context, text string, voice openai.SpeechVoice) (*opus.Stream, error) {
 	slog.Info("speech request started")
-	speechResp, err := oai.Client.CreateSpeech(context.Background(), openai.CreateSpeechRequest{
+	speechResp, err := oai.Client.CreateSpeech(ctx, openai.CreateSpeechRequest{
 		Model:          openai.TTSModel1HD,
 		Input:          text,
 		Voice:          voice,
@@ -116,10 +116,14 @@ func createAudioResponse(text string, voice openai.SpeechVoice) (*opus.Stream, e
 	return opus.NewStream(speechResp)
 }
 
-func speakAudio(voiceConn *discordgo.VoiceConnection, opusInput *opus.Stream) {
+// speakAudio plays the opus input in the voice channel until it ends or ctx is done.
+// Returns how long the audio played and whether it was played completely.
+func speakAudio(ctx context.Context, voiceConn *discordgo.VoiceConnection, opusInput *opus.Stream) (played time.Duration, complete bool) {
+	if ctx.Err() != nil {
+		return 0, false
+	}
 	voiceConn.Speaking(true)
 	defer voiceConn.Speaking(false)
-outer:
 	for {
 		// First we read the next streamed response from the http client.
 		// There can be multiple chunks as the response is chunk transfer encoded
@@ -129,7 +133,7 @@ outer:
 			if !errors.Is(err, io.EOF) {
 				slog.Error("could not decode OpenAI speech response with opus", "error", err)
 			}
-			break
+			return played, true
 		}
 		pcmBuf = pcmBuf[:n]
 		resampleBuf := make([]float32, len(pcmBuf)*5)
@@ -143,12 +147,17 @@ outer:
 			n, err = discordEncoder.EncodeFloat32(onePackage, buf)
 			if err != nil {
 				slog.Error("could not encode opus data to send to Discord", "error", err)
-				break outer
+				return played, true
 			}
 			encodedPackages = append(encodedPackages, buf[:n])
 		}
 		for _, pkg := range encodedPackages {
-			voiceConn.OpusSend <- pkg
+			select {
+			case voiceConn.OpusSend <- pkg:
+				played += discordAudioFrameSizeMs * time.Millisecond
+			case <-ctx.Done():
+				return played, false
+			}
 		}
 	}
 }
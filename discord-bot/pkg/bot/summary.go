@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/pnp"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Limits of Discord embeds, see https://discord.com/developers/docs/resources/message#embed-object-embed-limits
+const (
+	embedDescriptionLimit = 4096
+	embedFieldValueLimit  = 1024
+	// embedTotalLimit for the characters of all texts of an embed combined.
+	embedTotalLimit = 6000
+)
+
+// summaryAttachedNote is the footer of summary embeds that had to be shortened.
+const summaryAttachedNote = "Shortened to fit into Discord, the full summary is attached."
+
+// summaryEmbed renders the session summary with the scenes as description and a field for every other section.
+// Returns true if any text had to be shortened or sections had to be left out, so the full summary should be attached.
+func summaryEmbed(summary pnp.SessionSummary) (*discordgo.MessageEmbed, bool) {
+	scenes := make([]string, 0, len(summary.Scenes))
+	for i, scene := range summary.Scenes {
+		scenes = append(scenes, fmt.Sprintf("%d. %s", i+1, scene))
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("%s – session of %s", summary.Campaign, summary.StartedAt.Format("2006-01-02")),
+		Description: strings.Join(scenes, "\n"),
+		Timestamp:   summary.StartedAt.Format(time.RFC3339),
+	}
+
+	npcs := make([]string, 0, len(summary.NPCs))
+	for _, npc := range summary.NPCs {
+		npcs = append(npcs, fmt.Sprintf("**%s**: %s", npc.Name, npc.Description))
+	}
+	embed.Fields = appendListField(embed.Fields, "NPCs met", npcs)
+	embed.Fields = appendListField(embed.Fields, "Locations", summary.Locations)
+	embed.Fields = appendListField(embed.Fields, "Items gained", itemChanges(summary.ItemsGained))
+	embed.Fields = appendListField(embed.Fields, "Items lost", itemChanges(summary.ItemsLost))
+	embed.Fields = appendListField(embed.Fields, "Open threads", summary.OpenThreads)
+	quotes := make([]string, 0, len(summary.Quotes))
+	for _, quote := range summary.Quotes {
+		quotes = append(quotes, fmt.Sprintf("*%q* – %s", quote.Text, quote.Speaker))
+	}
+	embed.Fields = appendListField(embed.Fields, "Quotes", quotes)
+
+	var shortened, cut bool
+	embed.Description, shortened = truncate(embed.Description, embedDescriptionLimit)
+	for _, field := range embed.Fields {
+		field.Value, cut = truncate(field.Value, embedFieldValueLimit)
+		shortened = shortened || cut
+	}
+	if !shortened && embedLength(embed) <= embedTotalLimit {
+		return embed, false
+	}
+	embed.Footer = &discordgo.MessageEmbedFooter{Text: summaryAttachedNote}
+	// Quotes and open threads are the least important for the chronicle, so the last sections are left out first.
+	for len(embed.Fields) > 0 && embedLength(embed) > embedTotalLimit {
+		embed.Fields = embed.Fields[:len(embed.Fields)-1]
+	}
+	if excess := embedLength(embed) - embedTotalLimit; excess > 0 {
+		embed.Description, _ = truncate(embed.Description, utf8.RuneCountInString(embed.Description)-excess)
+	}
+	return embed, true
+}
+
+// embedLength counts the characters of all texts of the embed that Discord limits in total.
+func embedLength(embed *discordgo.MessageEmbed) int {
+	length := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	for _, field := range embed.Fields {
+		length += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	if embed.Footer != nil {
+		length += utf8.RuneCountInString(embed.Footer.Text)
+	}
+	return length
+}
+
+func itemChanges(changes []pnp.ItemChange) []string {
+	rendered := make([]string, 0, len(changes))
+	for _, change := range changes {
+		rendered = append(rendered, fmt.Sprintf("%s (%s)", change.Item, change.Who))
+	}
+	return rendered
+}
+
+// appendListField with the entries as bullet list. Empty lists are left out.
+func appendListField(fields []*discordgo.MessageEmbedField, name string, entries []string) []*discordgo.MessageEmbedField {
+	if len(entries) == 0 {
+		return fields
+	}
+	return append(fields, &discordgo.MessageEmbedField{
+		Name:  name,
+		Value: "- " + strings.Join(entries, "\n- "),
+	})
+}
+
+// truncate the text to at most limit runes, marking the cut with an ellipsis. Returns true if the text was cut.
+func truncate(text string, limit int) (string, bool) {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text, false
+	}
+	return string(runes[:max(limit-1, 0)]) + "…", true
+}
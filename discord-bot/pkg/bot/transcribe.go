@@ -142,7 +142,7 @@ func transcribeHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		select {
 		case respI := <-componentButtons[i.GuildID]["stop_transcript"]:
 			defer func() {
-				if err := vecdb.DefaultClient().StoreText(resolvedOptions["campaign"].(string), entireTranscript); err != nil {
+				if err := vecdb.DefaultClient().StoreText(resolvedOptions["campaign"].(string), startTime.Format(time.RFC3339), entireTranscript); err != nil {
 					slog.Warn("unexpected error while storing transcript in vector db", "campaign", resolvedOptions["campaign"], "error", err)
 				}
 				// Cleanup
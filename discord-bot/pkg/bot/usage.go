@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/usage"
+	"github.com/bwmarrin/discordgo"
+)
+
+var usageCommand = discordgo.ApplicationCommand{
+	Name:        "usage",
+	Description: "Show the token and speech usage of a campaign and what it cost.",
+	Options:     optionsByName("campaign"),
+}
+
+func usageHandler(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+	resolvedOptions := resolveAllOptions(data.Options, "campaign")
+	name := resolvedOptions["campaign"].(string)
+
+	var report usage.Report
+	var budget usage.Budget
+	if session, ok := activeCampaign(i.GuildID); ok && session.campaign.Name == name {
+		report, budget = session.campaign.Usage()
+	} else {
+		ledger, err := usage.OpenLedger(name + "-usage.yml")
+		if err != nil {
+			slog.Warn("could not open usage ledger", "campaign", name, "error", err)
+			err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "The usage of this campaign could not be read",
+					Flags:   discordgo.MessageFlagsEphemeral,
+				},
+			})
+			if err != nil {
+				slog.Warn("could not create interaction response", "error", err)
+			}
+			return
+		}
+		report, budget = ledger.Report(), usage.DefaultBudget()
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: formatUsage(name, report, budget),
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		slog.Warn("could not create interaction response", "error", err)
+	}
+}
+
+// formatUsage of the campaign with the total, the current session and its actors.
+func formatUsage(name string, report usage.Report, budget usage.Budget) string {
+	if len(report.Sessions) == 0 {
+		return fmt.Sprintf("There is no recorded usage for campaign **%s** yet.", name)
+	}
+	prices := budget.EffectivePrices()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**%s** over %d sessions: $%.2f\n-# %s\n", name, len(report.Sessions), report.Models.Cost(prices), report.Total)
+
+	current := report.Current()
+	fmt.Fprintf(&sb, "**Session of %s**: $%.2f\n", current.StartedAt.Format("2006-01-02 15:04"), current.Models.Cost(prices))
+	actors := make([]string, 0, len(current.Actors))
+	for actor := range current.Actors {
+		actors = append(actors, actor)
+	}
+	slices.Sort(actors)
+	for _, actor := range actors {
+		models := current.Actors[actor]
+		counts := models.Total()
+		fmt.Fprintf(&sb, "- %s: $%.2f (%d tokens, %d TTS characters)\n", actor, models.Cost(prices),
+			counts.PromptTokens+counts.CompletionTokens, counts.TTSCharacters)
+	}
+
+	switch budget.Level(report.Models) {
+	case usage.HardLimit:
+		fmt.Fprintf(&sb, "Hard limit of $%.2f reached, actors stay silent.", budget.Hard)
+	case usage.SoftLimit:
+		fmt.Fprintf(&sb, "Soft limit of $%.2f reached, actors use the cheap model %q.", budget.Soft, budget.CheapModel)
+	default:
+		if budget.Soft > 0 || budget.Hard > 0 {
+			fmt.Fprintf(&sb, "Budget: soft limit $%.2f, hard limit $%.2f", budget.Soft, budget.Hard)
+		}
+	}
+	return sb.String()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/usage"
 	"gopkg.in/yaml.v3"
 )
 
@@ -36,6 +37,29 @@ type OpenAI struct {
 	Token string
 }
 
+// ChatModel configuration options for the LLM that actors and summaries use.
+type ChatModel struct {
+	// Model name. Defaults to gpt-4o if BaseURL is empty and is required otherwise.
+	Model string `yaml:"model"`
+	// BaseURL of an OpenAI compatible API like llama.cpp server, Ollama or vLLM. Leave empty to use OpenAI.
+	BaseURL string `yaml:"baseURL"`
+	// Token to access the API. Defaults to the OpenAI token if BaseURL is empty.
+	Token string `yaml:"token"`
+}
+
+// Budget of each campaign in US dollars over all of its sessions. Zero limits are disabled.
+type Budget struct {
+	// Soft limit at which actors switch to the CheapModel.
+	Soft float64 `yaml:"soft"`
+	// Hard limit at which actors stop responding.
+	Hard float64 `yaml:"hard"`
+	// CheapModel that actors use once the soft limit is reached. Actors keep their model if empty.
+	CheapModel string `yaml:"cheapModel"`
+	// Prices to calculate the costs with by model name. The entry "default" prices all other models as well as
+	// speech and embeddings. Defaults to the prices of gpt-4o, gpt-4o-mini, tts-1-hd and text-embedding-ada-002 if omitted.
+	Prices usage.Prices `yaml:"prices"`
+}
+
 type Weaviate struct {
 	Scheme  string
 	Address string
@@ -48,9 +72,11 @@ type SpeechToText struct {
 // App encapsulates the entire application config.
 type App struct {
 	OpenAI       OpenAI       `yaml:"openAI"`
+	ChatModel    ChatModel    `yaml:"chatModel"`
 	Agent        Agent        `yaml:"agent"`
 	SpeechToText SpeechToText `yaml:"speechToText"`
 	Weaviate     Weaviate     `yaml:"weaviate"`
+	Budget       Budget       `yaml:"budget"`
 }
 
 type ContextKey uint
@@ -0,0 +1,298 @@
+// Package dice rolls dice from the common pen & paper notation like "2d6+3", "4d6kh3", "d20 adv", "3d6!" or "4dF".
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidNotation will be returned if a notation can't be parsed.
+var ErrInvalidNotation = errors.New("invalid dice notation")
+
+const (
+	// maxDice that a single term may roll to prevent abuse.
+	maxDice = 100
+	// maxExplosions of a single term so exploding dice always end.
+	maxExplosions = 100
+)
+
+// Selection of the dice of a term that count towards the total.
+type Selection string
+
+const (
+	// KeepHighest dice like in "4d6kh3".
+	KeepHighest Selection = "kh"
+	// KeepLowest dice like in "2d20kl1".
+	KeepLowest Selection = "kl"
+	// DropHighest dice like in "4d6dh1".
+	DropHighest Selection = "dh"
+	// DropLowest dice like in "4d6dl1".
+	DropLowest Selection = "dl"
+)
+
+// Term of a dice notation like "2d6", "4d6kh3" or "+3".
+type Term struct {
+	// Count of dice to roll. 0 for constant modifiers.
+	Count int
+	// Sides of each die.
+	Sides int
+	// Fate dice have the sides -1, 0 and +1.
+	Fate bool
+	// Explode rolls another die whenever a die shows its highest side.
+	Explode bool
+	// Select which dice count towards the total. Empty to count all dice.
+	Select Selection
+	// SelectCount of dice that will be kept or dropped.
+	SelectCount int
+	// Modifier for constant terms.
+	Modifier int
+	// Negative terms will be subtracted from the total.
+	Negative bool
+}
+
+// Die that was rolled.
+type Die struct {
+	// Value that the die shows.
+	Value int
+	// Dropped dice don't count towards the total.
+	Dropped bool
+	// Exploded dice caused another die to be rolled.
+	Exploded bool
+	// Fate dice are rendered as +, - or 0.
+	Fate bool
+}
+
+// String renders the die like "4", exploded dice like "6!" and dropped dice like "(1)".
+func (d Die) String() string {
+	value := strconv.Itoa(d.Value)
+	if d.Fate {
+		value = [...]string{"-", "0", "+"}[d.Value+1]
+	}
+	if d.Exploded {
+		value += "!"
+	}
+	if d.Dropped {
+		return "(" + value + ")"
+	}
+	return value
+}
+
+// Result of a roll.
+type Result struct {
+	// Notation that was rolled.
+	Notation string
+	// Rolls of each dice term in the order of the notation. Constant terms have no rolls.
+	Rolls [][]Die
+	// Total of all terms.
+	Total int
+}
+
+// String renders the result like "4d6kh3+2: [6 5 3 (1)] = 16".
+func (r Result) String() string {
+	rolls := make([]string, 0, len(r.Rolls))
+	for _, termRolls := range r.Rolls {
+		if len(termRolls) > 0 {
+			rolls = append(rolls, fmt.Sprint(termRolls))
+		}
+	}
+	return fmt.Sprintf("%s: %s = %d", r.Notation, strings.Join(rolls, " "), r.Total)
+}
+
+var diceTermPattern = regexp.MustCompile(`^(\d*)d(\d+|f|%)(!?)(?:(kh|kl|dh|dl|k)(\d*))?$`)
+
+// Parse the notation into its terms. A trailing "adv" or "dis" rolls the first single die twice and keeps the higher or lower one.
+func Parse(notation string) ([]Term, error) {
+	text := strings.ToLower(strings.TrimSpace(notation))
+	var advantage Selection
+	for suffix, selection := range map[string]Selection{"advantage": KeepHighest, "adv": KeepHighest, "disadvantage": KeepLowest, "dis": KeepLowest} {
+		if rest, ok := strings.CutSuffix(text, suffix); ok && strings.HasSuffix(rest, " ") {
+			text, advantage = rest, selection
+			break
+		}
+	}
+	text = strings.ReplaceAll(text, " ", "")
+	if text == "" {
+		return nil, fmt.Errorf("%w: empty notation", ErrInvalidNotation)
+	}
+	terms := make([]Term, 0)
+	for text != "" {
+		negative := false
+		switch text[0] {
+		case '-':
+			negative = true
+			text = text[1:]
+		case '+':
+			text = text[1:]
+		}
+		end := strings.IndexAny(text, "+-")
+		if end < 0 {
+			end = len(text)
+		}
+		term, err := parseTerm(text[:end])
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrInvalidNotation, notation, err)
+		}
+		term.Negative = negative
+		terms = append(terms, term)
+		text = text[end:]
+	}
+	if advantage != "" {
+		i := slices.IndexFunc(terms, func(t Term) bool { return t.Count == 1 && t.Select == "" })
+		if i < 0 {
+			return nil, fmt.Errorf("%w %q: advantage and disadvantage need a single die like d20", ErrInvalidNotation, notation)
+		}
+		terms[i].Count = 2
+		terms[i].Select = advantage
+		terms[i].SelectCount = 1
+	}
+	return terms, nil
+}
+
+func parseTerm(text string) (Term, error) {
+	if !strings.Contains(text, "d") {
+		modifier, err := strconv.Atoi(text)
+		if err != nil {
+			return Term{}, fmt.Errorf("%q is neither dice nor a number", text)
+		}
+		return Term{Modifier: modifier}, nil
+	}
+	match := diceTermPattern.FindStringSubmatch(text)
+	if match == nil {
+		return Term{}, fmt.Errorf("%q is not a valid dice term", text)
+	}
+	count, sides, explode, selection, selectCount := match[1], match[2], match[3], match[4], match[5]
+	term := Term{Count: 1, Explode: explode != ""}
+	if count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 || n > maxDice {
+			return Term{}, fmt.Errorf("dice count must be between 1 and %d but is %q", maxDice, count)
+		}
+		term.Count = n
+	}
+	switch sides {
+	case "f":
+		term.Fate = true
+		term.Sides = 3
+	case "%":
+		term.Sides = 100
+	default:
+		n, err := strconv.Atoi(sides)
+		if err != nil || n < 1 {
+			return Term{}, fmt.Errorf("dice sides must be a positive number but are %q", sides)
+		}
+		term.Sides = n
+	}
+	if term.Explode && (term.Fate || term.Sides < 2) {
+		return Term{}, fmt.Errorf("%q can't explode", text)
+	}
+	if selection != "" {
+		term.Select = Selection(selection)
+		if selection == "k" {
+			term.Select = KeepHighest
+		}
+		term.SelectCount = 1
+		if selectCount != "" {
+			n, err := strconv.Atoi(selectCount)
+			if err != nil || n < 1 {
+				return Term{}, fmt.Errorf("number of dice to keep or drop must be positive but is %q", selectCount)
+			}
+			term.SelectCount = n
+		}
+	}
+	return term, nil
+}
+
+// Roll the notation using rng.
+func Roll(rng *rand.Rand, notation string) (Result, error) {
+	terms, err := Parse(notation)
+	if err != nil {
+		return Result{}, err
+	}
+	res := Result{
+		Notation: strings.TrimSpace(notation),
+		Rolls:    make([][]Die, len(terms)),
+	}
+	for i, term := range terms {
+		rolls := rollTerm(rng, term)
+		value := term.Modifier
+		for _, die := range rolls {
+			if !die.Dropped {
+				value += die.Value
+			}
+		}
+		if term.Negative {
+			value = -value
+		}
+		res.Rolls[i] = rolls
+		res.Total += value
+	}
+	return res, nil
+}
+
+func rollTerm(rng *rand.Rand, term Term) []Die {
+	rolls := make([]Die, 0, term.Count)
+	explosions := 0
+	for i := 0; i < term.Count+explosions; i++ {
+		die := Die{Value: rng.Intn(term.Sides) + 1, Fate: term.Fate}
+		if term.Fate {
+			die.Value -= 2
+		}
+		if term.Explode && die.Value == term.Sides && explosions < maxExplosions {
+			die.Exploded = true
+			explosions++
+		}
+		rolls = append(rolls, die)
+	}
+	if term.Select == "" {
+		return rolls
+	}
+
+	// Indexes of the dice from lowest to highest
+	order := make([]int, len(rolls))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return rolls[a].Value - rolls[b].Value })
+	n := min(term.SelectCount, len(rolls))
+	var dropped []int
+	switch term.Select {
+	case KeepHighest:
+		dropped = order[:len(order)-n]
+	case KeepLowest:
+		dropped = order[n:]
+	case DropHighest:
+		dropped = order[len(order)-n:]
+	case DropLowest:
+		dropped = order[:n]
+	}
+	for _, i := range dropped {
+		rolls[i].Dropped = true
+	}
+	return rolls
+}
+
+// Roller rolls dice with its own random source. It is safe for concurrent use.
+type Roller struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewRoller with the given seed. The same seed will roll the same results.
+func NewRoller(seed int64) *Roller {
+	return &Roller{
+		rng: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Roll the notation.
+func (r *Roller) Roll(notation string) (Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return Roll(r.rng, notation)
+}
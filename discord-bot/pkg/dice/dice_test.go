@@ -0,0 +1,137 @@
+package dice
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestRollTotals(t *testing.T) {
+	tests := []struct {
+		notation string
+		min, max int
+	}{
+		{"1d20", 1, 20},
+		{"d6", 1, 6},
+		{"2d6+3", 5, 15},
+		{"1d8 - 1d4 + 2", -1, 9},
+		{"5", 5, 5},
+		{"4d6kh3+2", 5, 20},
+		{"2d20kl1", 1, 20},
+		{"d20+5 adv", 6, 25},
+		{"d20 dis", 1, 20},
+		{"4dF", -4, 4},
+		{"d%", 1, 100},
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, test := range tests {
+		for range 100 {
+			res, err := Roll(rng, test.notation)
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %v", test.notation, err)
+			}
+			if res.Total < test.min || res.Total > test.max {
+				t.Fatalf("expected %q to be between %d and %d but got %s", test.notation, test.min, test.max, res)
+			}
+		}
+	}
+}
+
+func TestRollIsDeterministic(t *testing.T) {
+	a, _ := Roll(rand.New(rand.NewSource(42)), "4d6")
+	b, _ := Roll(rand.New(rand.NewSource(42)), "4d6")
+	if a.String() != b.String() {
+		t.Errorf("expected the same seed to roll the same but got %s and %s", a, b)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, notation := range []string{"", "d", "2d", "0d6", "1000d6", "2x6", "1d-6", "d20!!", "1dF!", "4d6kh0", "2d6 adv", "d20 adv dis"} {
+		if _, err := Parse(notation); !errors.Is(err, ErrInvalidNotation) {
+			t.Errorf("expected %q to be invalid but got %v", notation, err)
+		}
+	}
+}
+
+func TestRollSelection(t *testing.T) {
+	tests := []struct {
+		notation string
+		keep     func(sorted []int) []int
+	}{
+		{"4d6kh3", func(sorted []int) []int { return sorted[1:] }},
+		{"4d6k3", func(sorted []int) []int { return sorted[1:] }},
+		{"4d6kl1", func(sorted []int) []int { return sorted[:1] }},
+		{"4d6dh1", func(sorted []int) []int { return sorted[:3] }},
+		{"4d6dl2", func(sorted []int) []int { return sorted[2:] }},
+	}
+	rng := rand.New(rand.NewSource(7))
+	for _, test := range tests {
+		for range 50 {
+			res, err := Roll(rng, test.notation)
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %v", test.notation, err)
+			}
+			values := make([]int, 0, 4)
+			for _, die := range res.Rolls[0] {
+				values = append(values, die.Value)
+			}
+			slices.Sort(values)
+			expected := 0
+			for _, v := range test.keep(values) {
+				expected += v
+			}
+			if res.Total != expected {
+				t.Fatalf("expected %q to total %d but got %s", test.notation, expected, res)
+			}
+		}
+	}
+}
+
+func TestRollExploding(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for range 100 {
+		res, err := Roll(rng, "2d2!")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		exploded := 0
+		for _, die := range res.Rolls[0] {
+			if die.Exploded {
+				exploded++
+				if die.Value != 2 {
+					t.Fatalf("only dice showing their highest side may explode but got %s", res)
+				}
+			}
+		}
+		if len(res.Rolls[0]) != 2+exploded {
+			t.Fatalf("expected every explosion to roll another die but got %s", res)
+		}
+	}
+}
+
+func TestResultString(t *testing.T) {
+	res := Result{
+		Notation: "4d6kh3+2",
+		Rolls:    [][]Die{{{Value: 6, Exploded: true}, {Value: 5}, {Value: 1, Dropped: true}, {Value: 3}}, nil},
+		Total:    16,
+	}
+	if s := res.String(); s != "4d6kh3+2: [6! 5 (1) 3] = 16" {
+		t.Errorf("unexpected rendering %q", s)
+	}
+	fate := Result{Notation: "3dF", Rolls: [][]Die{{{Value: -1, Fate: true}, {Value: 0, Fate: true}, {Value: 1, Fate: true}}}}
+	if s := fate.String(); s != "3dF: [- 0 +] = 0" {
+		t.Errorf("unexpected fate rendering %q", s)
+	}
+}
+
+func TestRollerIsDeterministic(t *testing.T) {
+	a, b := NewRoller(42), NewRoller(42)
+	for range 10 {
+		resA, _ := a.Roll("d20 adv")
+		resB, _ := b.Roll("d20 adv")
+		if resA.String() != resB.String() {
+			t.Fatalf("expected rollers with the same seed to roll the same but got %s and %s", resA, resB)
+		}
+	}
+}
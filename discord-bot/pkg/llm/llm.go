@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Role of the author of a chat message.
+type Role string
+
+const (
+	// RoleSystem for instructions that steer the model.
+	RoleSystem Role = "system"
+	// RoleUser for input that the model should respond to.
+	RoleUser Role = "user"
+	// RoleAssistant for previous responses of the model.
+	RoleAssistant Role = "assistant"
+	// RoleTool for results of tool calls.
+	RoleTool Role = "tool"
+)
+
+// Message of a chat conversation.
+type Message struct {
+	Role    Role
+	Content string
+	// ToolCalls that the assistant requested in this message.
+	ToolCalls []ToolCall
+	// ToolCallID that this tool message is the result of.
+	ToolCallID string
+}
+
+// Tool that the model may call while generating a response.
+type Tool struct {
+	// Name of the tool.
+	Name string
+	// Description that tells the model when and how to use the tool.
+	Description string
+	// Parameters of the tool as JSON schema.
+	Parameters json.RawMessage
+}
+
+// ToolCall that the model requested.
+type ToolCall struct {
+	// ID that the tool result must refer to.
+	ID string
+	// Name of the tool to call.
+	Name string
+	// Arguments as JSON object.
+	Arguments string
+}
+
+// Request for a chat completion.
+type Request struct {
+	// Model to use. If empty the default model of the ChatModel will be used.
+	Model string
+	// Messages of the conversation so far.
+	Messages []Message
+	// MaxTokens that may be generated. 0 means no limit.
+	MaxTokens int
+	// Temperature for sampling. Nil uses the default of the model.
+	Temperature *float32
+	// TopP for nucleus sampling. 0 uses the default of the model.
+	TopP float32
+	// PresencePenalty for tokens that already appeared in the conversation.
+	PresencePenalty float32
+	// Stop sequences that end the generation.
+	Stop []string
+	// Tools that the model may call.
+	Tools []Tool
+	// JSON forces the model to respond with a JSON object. The messages must describe the expected object.
+	JSON bool
+}
+
+// Response of a chat completion.
+type Response struct {
+	// Content that the model generated.
+	Content string
+	// ToolCalls that the model requested. The results must be sent back in a follow up request.
+	ToolCalls []ToolCall
+	// Usage of the request. Zero if the API didn't report it.
+	Usage Usage
+}
+
+// Usage of tokens by a chat completion.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Stream of a chat completion that is generated incrementally.
+type Stream interface {
+	// Recv the next piece of generated content. Returns io.EOF once the generation is done.
+	Recv() (string, error)
+	// ToolCalls that the model requested. Only complete once Recv returned io.EOF.
+	ToolCalls() []ToolCall
+	// Usage of the request. Only available once Recv returned io.EOF and zero if the API didn't report it.
+	Usage() Usage
+	// Close the stream. Must be called once the stream is not needed anymore.
+	Close() error
+}
+
+// ChatModel is a backend that can generate chat completions.
+type ChatModel interface {
+	// Chat creates the next message for the given conversation.
+	Chat(ctx context.Context, req Request) (Response, error)
+	// ChatStream creates the next message for the given conversation and streams it while it is generated.
+	ChatStream(ctx context.Context, req Request) (Stream, error)
+}
+
+var defaultModel ChatModel
+
+// SetDefaultModel to override the DefaultModel().
+func SetDefaultModel(model ChatModel) {
+	defaultModel = model
+}
+
+// DefaultModel to generate chat completions with. Will be nil if SetDefaultModel was not called yet.
+func DefaultModel() ChatModel {
+	return defaultModel
+}
+
+// ResolveModel returns the model that the chat model will use for a request with the given model.
+// Empty models resolve to the default model of the chat model if it tells it, like OpenAI does.
+func ResolveModel(chatModel ChatModel, model string) string {
+	if model != "" {
+		return model
+	}
+	if named, ok := chatModel.(interface{ Model() string }); ok {
+		return named.Model()
+	}
+	return ""
+}
@@ -0,0 +1,223 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ErrNoChoices will be returned if the API responded without any generated message.
+var ErrNoChoices = errors.New("chat completion returned no choices")
+
+// ErrNoModel will be returned if an OpenAI compatible API is configured without a model name.
+var ErrNoModel = errors.New("model name must not be empty")
+
+// OpenAI is a ChatModel that talks to the OpenAI API or any other API that is compatible to it.
+type OpenAI struct {
+	client *openai.Client
+	model  string
+}
+
+// NewOpenAI creates a ChatModel using the official OpenAI API. If model is empty GPT-4o will be used.
+func NewOpenAI(token, model string) *OpenAI {
+	if model == "" {
+		model = openai.GPT4o
+	}
+	return &OpenAI{
+		client: openai.NewClient(token),
+		model:  model,
+	}
+}
+
+// NewOpenAICompatible creates a ChatModel for a server that implements the OpenAI chat completion API
+// like the llama.cpp server, Ollama or vLLM. The baseURL must point to the API root, e.g. http://localhost:11434/v1.
+// The token can be empty if the server doesn't require authentication. The model is required as these servers have no common default.
+func NewOpenAICompatible(baseURL, token, model string) (*OpenAI, error) {
+	if model == "" {
+		return nil, fmt.Errorf("could not use API at %s: %w", baseURL, ErrNoModel)
+	}
+	cfg := openai.DefaultConfig(token)
+	cfg.BaseURL = baseURL
+	return &OpenAI{
+		client: openai.NewClientWithConfig(cfg),
+		model:  model,
+	}, nil
+}
+
+// Model that will be used if a request doesn't specify one.
+func (o *OpenAI) Model() string {
+	return o.model
+}
+
+// Chat implements ChatModel.
+func (o *OpenAI) Chat(ctx context.Context, req Request) (Response, error) {
+	resp, err := o.client.CreateChatCompletion(ctx, o.toOpenAIRequest(req))
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to create chat completion: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return Response{}, ErrNoChoices
+	}
+	return Response{
+		Content:   resp.Choices[0].Message.Content,
+		ToolCalls: fromOpenAIToolCalls(resp.Choices[0].Message.ToolCalls),
+		Usage: Usage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+// ChatStream implements ChatModel.
+func (o *OpenAI) ChatStream(ctx context.Context, req Request) (Stream, error) {
+	openaiReq := o.toOpenAIRequest(req)
+	openaiReq.Stream = true
+	openaiReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	stream, err := o.client.CreateChatCompletionStream(ctx, openaiReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat completion stream: %w", err)
+	}
+	return &openAIStream{stream: stream}, nil
+}
+
+type openAIStream struct {
+	stream    *openai.ChatCompletionStream
+	toolCalls []ToolCall
+	usage     Usage
+}
+
+// Recv implements Stream.
+func (s *openAIStream) Recv() (string, error) {
+	for {
+		resp, err := s.stream.Recv()
+		if err != nil {
+			return "", err
+		}
+		if resp.Usage != nil {
+			// Sent with the last chunk that has no choices
+			s.usage = Usage{
+				PromptTokens:     resp.Usage.PromptTokens,
+				CompletionTokens: resp.Usage.CompletionTokens,
+			}
+		}
+		if len(resp.Choices) == 0 {
+			continue
+		}
+		s.addToolCallDeltas(resp.Choices[0].Delta.ToolCalls)
+		if resp.Choices[0].Delta.Content == "" {
+			// Role announcements, tool calls and empty keep alive chunks
+			continue
+		}
+		return resp.Choices[0].Delta.Content, nil
+	}
+}
+
+// ToolCalls implements Stream.
+func (s *openAIStream) ToolCalls() []ToolCall {
+	return s.toolCalls
+}
+
+// Usage implements Stream.
+func (s *openAIStream) Usage() Usage {
+	return s.usage
+}
+
+// addToolCallDeltas to the tool calls of the stream. Each tool call is streamed in multiple pieces that share the same index.
+func (s *openAIStream) addToolCallDeltas(deltas []openai.ToolCall) {
+	for _, delta := range deltas {
+		index := len(s.toolCalls) - 1
+		if delta.Index != nil {
+			index = *delta.Index
+		} else if delta.ID != "" || index < 0 {
+			index = len(s.toolCalls)
+		}
+		for len(s.toolCalls) <= index {
+			s.toolCalls = append(s.toolCalls, ToolCall{})
+		}
+		call := &s.toolCalls[index]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		call.Name += delta.Function.Name
+		call.Arguments += delta.Function.Arguments
+	}
+}
+
+// Close implements Stream.
+func (s *openAIStream) Close() error {
+	return s.stream.Close()
+}
+
+func (o *OpenAI) toOpenAIRequest(req Request) openai.ChatCompletionRequest {
+	model := req.Model
+	if model == "" {
+		model = o.model
+	}
+	messages := make([]openai.ChatCompletionMessage, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = openai.ChatCompletionMessage{
+			Role:       string(msg.Role),
+			Content:    msg.Content,
+			ToolCallID: msg.ToolCallID,
+		}
+		for _, call := range msg.ToolCalls {
+			messages[i].ToolCalls = append(messages[i].ToolCalls, openai.ToolCall{
+				ID:   call.ID,
+				Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{
+					Name:      call.Name,
+					Arguments: call.Arguments,
+				},
+			})
+		}
+	}
+	var tools []openai.Tool
+	for _, tool := range req.Tools {
+		tools = append(tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	var responseFormat *openai.ChatCompletionResponseFormat
+	if req.JSON {
+		responseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+	var temperature float32
+	if req.Temperature != nil {
+		temperature = *req.Temperature
+		if temperature == 0 {
+			// The client omits a temperature of 0 which would use the default of the model instead.
+			temperature = math.SmallestNonzeroFloat32
+		}
+	}
+	return openai.ChatCompletionRequest{
+		Model:           model,
+		Messages:        messages,
+		MaxTokens:       req.MaxTokens,
+		Temperature:     temperature,
+		TopP:            req.TopP,
+		PresencePenalty: req.PresencePenalty,
+		Stop:            req.Stop,
+		Tools:           tools,
+		ResponseFormat:  responseFormat,
+	}
+}
+
+func fromOpenAIToolCalls(calls []openai.ToolCall) []ToolCall {
+	var toolCalls []ToolCall
+	for _, call := range calls {
+		toolCalls = append(toolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	return toolCalls
+}
@@ -0,0 +1,237 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func newStandIn(t *testing.T, answer string, received *openai.ChatCompletionRequest) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected request path %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(received); err != nil {
+			t.Errorf("could not decode chat completion request: %v", err)
+		}
+		if received.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, word := range strings.SplitAfter(answer, " ") {
+				chunk, _ := json.Marshal(openai.ChatCompletionStreamResponse{
+					Choices: []openai.ChatCompletionStreamChoice{
+						{Delta: openai.ChatCompletionStreamChoiceDelta{Content: word}},
+					},
+				})
+				fmt.Fprintf(w, "data: %s\n\n", chunk)
+			}
+			if received.StreamOptions != nil && received.StreamOptions.IncludeUsage {
+				chunk, _ := json.Marshal(openai.ChatCompletionStreamResponse{
+					Usage: &openai.Usage{PromptTokens: 12, CompletionTokens: 7},
+				})
+				fmt.Fprintf(w, "data: %s\n\n", chunk)
+			}
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Model: received.Model,
+			Usage: openai.Usage{PromptTokens: 12, CompletionTokens: 7},
+			Choices: []openai.ChatCompletionChoice{
+				{
+					Message: openai.ChatCompletionMessage{
+						Role:    openai.ChatMessageRoleAssistant,
+						Content: answer,
+					},
+				},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newLocalModel that talks to the stand-in.
+func newLocalModel(t *testing.T, srv *httptest.Server) *OpenAI {
+	t.Helper()
+	model, err := NewOpenAICompatible(srv.URL+"/v1", "", "local-model")
+	if err != nil {
+		t.Fatalf("could not create chat model: %v", err)
+	}
+	return model
+}
+
+func TestOpenAICompatibleChat(t *testing.T) {
+	var received openai.ChatCompletionRequest
+	srv := newStandIn(t, "Hello traveller!", &received)
+
+	model := newLocalModel(t, srv)
+	resp, err := model.Chat(context.Background(), Request{
+		Messages: []Message{
+			{Role: RoleSystem, Content: "You are a shopkeeper."},
+			{Role: RoleUser, Content: "Tharkhan: Hello!"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error during chat: %v", err)
+	}
+	if resp.Content != "Hello traveller!" {
+		t.Errorf("expected response content %q but got %q", "Hello traveller!", resp.Content)
+	}
+	if resp.Usage != (Usage{PromptTokens: 12, CompletionTokens: 7}) {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+	if received.Model != "local-model" {
+		t.Errorf("expected default model local-model to be requested but got %q", received.Model)
+	}
+	if len(received.Messages) != 2 || received.Messages[0].Role != openai.ChatMessageRoleSystem || received.Messages[1].Content != "Tharkhan: Hello!" {
+		t.Errorf("messages were not passed on correctly: %+v", received.Messages)
+	}
+}
+
+func TestRequestModelOverridesDefault(t *testing.T) {
+	var received openai.ChatCompletionRequest
+	srv := newStandIn(t, "ok", &received)
+
+	model := newLocalModel(t, srv)
+	if _, err := model.Chat(context.Background(), Request{Model: "other-model"}); err != nil {
+		t.Fatalf("unexpected error during chat: %v", err)
+	}
+	if received.Model != "other-model" {
+		t.Errorf("expected requested model other-model but got %q", received.Model)
+	}
+}
+
+func TestJSONRequestsJSONObject(t *testing.T) {
+	var received openai.ChatCompletionRequest
+	srv := newStandIn(t, "{}", &received)
+
+	model := newLocalModel(t, srv)
+	if _, err := model.Chat(context.Background(), Request{JSON: true}); err != nil {
+		t.Fatalf("unexpected error during chat: %v", err)
+	}
+	if received.ResponseFormat == nil || received.ResponseFormat.Type != openai.ChatCompletionResponseFormatTypeJSONObject {
+		t.Errorf("expected a JSON object to be requested but got %+v", received.ResponseFormat)
+	}
+}
+
+func TestNewOpenAIDefaultsToGPT4o(t *testing.T) {
+	if model := NewOpenAI("token", ""); model.Model() != openai.GPT4o {
+		t.Errorf("expected default model %s but got %s", openai.GPT4o, model.Model())
+	}
+}
+
+func TestOpenAICompatibleRequiresModel(t *testing.T) {
+	if _, err := NewOpenAICompatible("http://localhost:11434/v1", "", ""); !errors.Is(err, ErrNoModel) {
+		t.Errorf("expected an empty model to be rejected but got %v", err)
+	}
+}
+
+func TestResolveModel(t *testing.T) {
+	chatModel := NewOpenAI("", "gpt-4")
+	if model := ResolveModel(chatModel, "gpt-4o-mini"); model != "gpt-4o-mini" {
+		t.Errorf("expected the requested model but got %q", model)
+	}
+	if model := ResolveModel(chatModel, ""); model != "gpt-4" {
+		t.Errorf("expected the default model of the chat model but got %q", model)
+	}
+	if model := ResolveModel(nil, ""); model != "" {
+		t.Errorf("expected no model without a chat model but got %q", model)
+	}
+}
+
+func TestOpenAICompatibleChatStream(t *testing.T) {
+	var received openai.ChatCompletionRequest
+	srv := newStandIn(t, "Hello traveller! What do you need?", &received)
+
+	model := newLocalModel(t, srv)
+	stream, err := model.ChatStream(context.Background(), Request{
+		Messages: []Message{{Role: RoleUser, Content: "Tharkhan: Hello!"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error while creating stream: %v", err)
+	}
+	defer stream.Close()
+
+	pieces := make([]string, 0)
+	for {
+		piece, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error while receiving stream: %v", err)
+		}
+		pieces = append(pieces, piece)
+	}
+	if len(pieces) != 6 || strings.Join(pieces, "") != "Hello traveller! What do you need?" {
+		t.Errorf("unexpected streamed pieces %q", pieces)
+	}
+	if !received.Stream {
+		t.Error("expected a streaming request")
+	}
+	if stream.Usage() != (Usage{PromptTokens: 12, CompletionTokens: 7}) {
+		t.Errorf("expected the usage of the stream to be reported but got %+v", stream.Usage())
+	}
+}
+
+func TestOpenAICompatibleChatStreamToolCalls(t *testing.T) {
+	var received openai.ChatCompletionRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("could not decode chat completion request: %v", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		index := 0
+		deltas := []openai.ToolCall{
+			{Index: &index, ID: "call_1", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "roll_dice"}},
+			{Index: &index, Function: openai.FunctionCall{Arguments: `{"notation":`}},
+			{Index: &index, Function: openai.FunctionCall{Arguments: `"1d20"}`}},
+		}
+		for _, delta := range deltas {
+			chunk, _ := json.Marshal(openai.ChatCompletionStreamResponse{
+				Choices: []openai.ChatCompletionStreamChoice{
+					{Delta: openai.ChatCompletionStreamChoiceDelta{ToolCalls: []openai.ToolCall{delta}}},
+				},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	model := newLocalModel(t, srv)
+	stream, err := model.ChatStream(context.Background(), Request{
+		Messages: []Message{{Role: RoleUser, Content: "Tharkhan: Roll for me!"}},
+		Tools: []Tool{{
+			Name:        "roll_dice",
+			Description: "Roll dice",
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"notation":{"type":"string"}}}`),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error while creating stream: %v", err)
+	}
+	defer stream.Close()
+	if piece, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		t.Fatalf("expected no content but got %q and %v", piece, err)
+	}
+
+	calls := stream.ToolCalls()
+	if len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Name != "roll_dice" || calls[0].Arguments != `{"notation":"1d20"}` {
+		t.Errorf("tool call deltas were not combined correctly: %+v", calls)
+	}
+	if len(received.Tools) != 1 || received.Tools[0].Function.Name != "roll_dice" {
+		t.Errorf("tools were not sent correctly: %+v", received.Tools)
+	}
+}
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Tokenizer counts the tokens that a text will take up in a prompt.
+type Tokenizer interface {
+	// CountTokens of the given text.
+	CountTokens(text string) int
+}
+
+// ApproxTokenizer estimates tokens without knowing the vocabulary of a model.
+// It assumes roughly 4 characters per token which is close for GPT models on english and german text.
+type ApproxTokenizer struct{}
+
+// CountTokens implements Tokenizer.
+func (ApproxTokenizer) CountTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+var contextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	// Longer prefixes must come first as the first match wins.
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-3.5-turbo", 16385},
+}
+
+// ContextWindow of the given model in tokens. Returns 0 if the model is unknown.
+func ContextWindow(model string) int {
+	for _, window := range contextWindows {
+		if strings.HasPrefix(model, window.prefix) {
+			return window.tokens
+		}
+	}
+	return 0
+}
@@ -0,0 +1,35 @@
+package llm
+
+import "testing"
+
+func TestApproxTokenizer(t *testing.T) {
+	tests := map[string]int{
+		"":          0,
+		"abc":       1,
+		"abcd":      1,
+		"abcde":     2,
+		"Hüsten ää": 3,
+	}
+	for text, expected := range tests {
+		if actual := (ApproxTokenizer{}).CountTokens(text); actual != expected {
+			t.Errorf("expected %q to count %d tokens but got %d", text, expected, actual)
+		}
+	}
+}
+
+func TestContextWindow(t *testing.T) {
+	tests := map[string]int{
+		"gpt-4o":         128000,
+		"gpt-4o-mini":    128000,
+		"gpt-4":          8192,
+		"gpt-4-32k-0613": 32768,
+		"gpt-3.5-turbo":  16385,
+		"llama3:8b":      0,
+		"":               0,
+	}
+	for model, expected := range tests {
+		if actual := ContextWindow(model); actual != expected {
+			t.Errorf("expected context window of %q to be %d but got %d", model, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package pnp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+)
+
+const (
+	defaultPromptTokens           = 6000
+	defaultMaxOldTranscripts      = 4
+	defaultMaxOldTranscriptTokens = 500
+	defaultMaxLoreTokens          = 1000
+)
+
+// PromptBudget limits how much transcript text an actor will see per response. Zero values use the defaults.
+type PromptBudget struct {
+	// MaxTokens for the system prompt, all old transcripts, the story so far and the current transcript.
+	// Defaults to 6000 but at most half of the models context window.
+	MaxTokens int `yaml:"maxTokens"`
+	// MaxOldTranscripts that will be included. Defaults to 4.
+	MaxOldTranscripts int `yaml:"maxOldTranscripts"`
+	// MaxOldTranscriptTokens per old transcript. Defaults to 500.
+	MaxOldTranscriptTokens int `yaml:"maxOldTranscriptTokens"`
+	// MaxLoreTokens for all lore. The least relevant lore is dropped first. Defaults to 1000.
+	MaxLoreTokens int `yaml:"maxLoreTokens"`
+}
+
+// BudgetReport tells what had to be dropped to fit a prompt context into its budget.
+type BudgetReport struct {
+	// Tokens of the fitted prompt context.
+	Tokens int
+	// Budget in tokens that the prompt context had to fit in. The system prompt is already subtracted.
+	Budget int
+	// DroppedLines from the start of the current transcript.
+	DroppedLines int
+	// DroppedOldTranscripts that were left out entirely.
+	DroppedOldTranscripts int
+	// TruncatedOldTranscripts that were shortened.
+	TruncatedOldTranscripts int
+	// DroppedLore chunks that were left out.
+	DroppedLore int
+}
+
+// Dropped returns true if anything had to be left out.
+func (r BudgetReport) Dropped() bool {
+	return r.DroppedLines > 0 || r.DroppedOldTranscripts > 0 || r.TruncatedOldTranscripts > 0 || r.DroppedLore > 0
+}
+
+// Validate that no limit is negative.
+func (b PromptBudget) Validate() error {
+	var err error
+	if b.MaxTokens < 0 {
+		err = errors.Join(err, fmt.Errorf("budget maxTokens must not be negative but is %d", b.MaxTokens))
+	}
+	if b.MaxOldTranscripts < 0 {
+		err = errors.Join(err, fmt.Errorf("budget maxOldTranscripts must not be negative but is %d", b.MaxOldTranscripts))
+	}
+	if b.MaxOldTranscriptTokens < 0 {
+		err = errors.Join(err, fmt.Errorf("budget maxOldTranscriptTokens must not be negative but is %d", b.MaxOldTranscriptTokens))
+	}
+	if b.MaxLoreTokens < 0 {
+		err = errors.Join(err, fmt.Errorf("budget maxLoreTokens must not be negative but is %d", b.MaxLoreTokens))
+	}
+	return err
+}
+
+// TokensFor the given model. Uses MaxTokens if set or the default that is capped at half of the models context window.
+func (b PromptBudget) TokensFor(model string) int {
+	if b.MaxTokens > 0 {
+		return b.MaxTokens
+	}
+	window := llm.ContextWindow(model)
+	if window > 0 && window/2 < defaultPromptTokens {
+		return window / 2
+	}
+	return defaultPromptTokens
+}
+
+// Fit the prompt context into the budget for the given model that is left after the system prompt.
+// Old transcripts are capped in count and size and lore is capped in size first, then the oldest lines of the current transcript are dropped.
+// The story so far is always kept as it is already condensed, just like the direction of the GM.
+// The latest line of the current transcript will always be kept, so old transcripts and then lore will be dropped if it alone exceeds the budget.
+func (b PromptBudget) Fit(tokenizer llm.Tokenizer, model, systemPrompt string, ctx PromptContext) (PromptContext, BudgetReport) {
+	report := BudgetReport{
+		Budget: max(b.TokensFor(model)-tokenizer.CountTokens(systemPrompt), 0),
+	}
+	maxOld := b.MaxOldTranscripts
+	if maxOld == 0 {
+		maxOld = defaultMaxOldTranscripts
+	}
+	maxOldTokens := b.MaxOldTranscriptTokens
+	if maxOldTokens == 0 {
+		maxOldTokens = defaultMaxOldTranscriptTokens
+	}
+	maxLoreTokens := b.MaxLoreTokens
+	if maxLoreTokens == 0 {
+		maxLoreTokens = defaultMaxLoreTokens
+	}
+
+	oldTranscripts := ctx.OldTranscripts
+	if len(oldTranscripts) > maxOld {
+		report.DroppedOldTranscripts = len(oldTranscripts) - maxOld
+		oldTranscripts = oldTranscripts[:maxOld]
+	}
+	fitted := PromptContext{
+		OldTranscripts: make([]string, 0, len(oldTranscripts)),
+		StorySoFar:     ctx.StorySoFar,
+		Direction:      ctx.Direction,
+	}
+	storyTokens := tokenizer.CountTokens(ctx.StorySoFar) + tokenizer.CountTokens(ctx.Direction)
+	oldTokens := make([]int, 0, len(oldTranscripts))
+	for _, old := range oldTranscripts {
+		lines, dropped := trimOldestLines(tokenizer, strings.Split(old, "\n"), maxOldTokens)
+		if len(lines) == 0 {
+			report.DroppedOldTranscripts++
+			continue
+		}
+		if dropped > 0 {
+			report.TruncatedOldTranscripts++
+		}
+		text := strings.Join(lines, "\n")
+		fitted.OldTranscripts = append(fitted.OldTranscripts, text)
+		oldTokens = append(oldTokens, tokenizer.CountTokens(text))
+	}
+	totalOldTokens := 0
+	for _, tokens := range oldTokens {
+		totalOldTokens += tokens
+	}
+
+	loreTokens := make([]int, 0, len(ctx.Lore))
+	totalLoreTokens := 0
+	for i, lore := range ctx.Lore {
+		tokens := tokenizer.CountTokens(lore)
+		if totalLoreTokens+tokens > maxLoreTokens {
+			report.DroppedLore = len(ctx.Lore) - i
+			break
+		}
+		fitted.Lore = append(fitted.Lore, lore)
+		loreTokens = append(loreTokens, tokens)
+		totalLoreTokens += tokens
+	}
+
+	currentLines := strings.Split(ctx.CurrentTranscript, "\n")
+	lines, dropped := trimOldestLines(tokenizer, currentLines, max(report.Budget-totalOldTokens-totalLoreTokens-storyTokens, 0))
+	if len(lines) == 0 && len(currentLines) > 0 {
+		// Keep the latest line and make room by dropping the least relevant old transcripts.
+		lines = currentLines[len(currentLines)-1:]
+		dropped = len(currentLines) - 1
+		lineTokens := tokenizer.CountTokens(lines[0])
+		for len(fitted.OldTranscripts) > 0 && totalOldTokens+totalLoreTokens+storyTokens+lineTokens > report.Budget {
+			last := len(fitted.OldTranscripts) - 1
+			totalOldTokens -= oldTokens[last]
+			fitted.OldTranscripts = fitted.OldTranscripts[:last]
+			oldTokens = oldTokens[:last]
+			report.DroppedOldTranscripts++
+		}
+		for len(fitted.Lore) > 0 && totalOldTokens+totalLoreTokens+storyTokens+lineTokens > report.Budget {
+			last := len(fitted.Lore) - 1
+			totalLoreTokens -= loreTokens[last]
+			fitted.Lore = fitted.Lore[:last]
+			loreTokens = loreTokens[:last]
+			report.DroppedLore++
+		}
+	}
+	report.DroppedLines = dropped
+	fitted.CurrentTranscript = strings.Join(lines, "\n")
+	report.Tokens = totalOldTokens + totalLoreTokens + storyTokens + tokenizer.CountTokens(fitted.CurrentTranscript)
+	return fitted, report
+}
+
+// trimOldestLines drops lines from the start until the remaining lines fit into maxTokens.
+// Returns the remaining lines and the number of dropped lines.
+func trimOldestLines(tokenizer llm.Tokenizer, lines []string, maxTokens int) ([]string, int) {
+	tokens := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		tokens += tokenizer.CountTokens(lines[i])
+		if i < len(lines)-1 {
+			// Account for the line break
+			tokens++
+		}
+		if tokens > maxTokens {
+			return lines[i+1:], i + 1
+		}
+	}
+	return lines, 0
+}
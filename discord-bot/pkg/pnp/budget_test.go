@@ -0,0 +1,126 @@
+package pnp
+
+import (
+	"strings"
+	"testing"
+)
+
+// wordTokenizer counts every word as one token.
+type wordTokenizer struct{}
+
+func (wordTokenizer) CountTokens(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestBudgetFitWithinBudget(t *testing.T) {
+	ctx := PromptContext{
+		OldTranscripts:    []string{"A: one two", "B: three"},
+		CurrentTranscript: "A: hello\nB: world",
+	}
+	fitted, report := PromptBudget{MaxTokens: 100}.Fit(wordTokenizer{}, "", "", ctx)
+	if report.Dropped() {
+		t.Errorf("nothing should have been dropped but got %+v", report)
+	}
+	if fitted.CurrentTranscript != ctx.CurrentTranscript || len(fitted.OldTranscripts) != 2 {
+		t.Errorf("prompt context should be unchanged but got %+v", fitted)
+	}
+	if report.Tokens != 9 {
+		t.Errorf("expected 9 tokens but got %d", report.Tokens)
+	}
+}
+
+func TestBudgetFitTrimsOldestLines(t *testing.T) {
+	ctx := PromptContext{
+		CurrentTranscript: "A: one one one\nB: two two\nA: three\nB: four",
+	}
+	fitted, report := PromptBudget{MaxTokens: 9}.Fit(wordTokenizer{}, "", "", ctx)
+	if fitted.CurrentTranscript != "B: two two\nA: three\nB: four" {
+		t.Errorf("expected the oldest line to be dropped but got:\n%s", fitted.CurrentTranscript)
+	}
+	if report.DroppedLines != 1 || report.Tokens > report.Budget {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestBudgetFitCapsOldTranscripts(t *testing.T) {
+	ctx := PromptContext{
+		OldTranscripts:    []string{"A: a\nB: b b b b", "C: c", "D: d", "E: e"},
+		CurrentTranscript: "A: now",
+	}
+	budget := PromptBudget{
+		MaxTokens:              100,
+		MaxOldTranscripts:      2,
+		MaxOldTranscriptTokens: 5,
+	}
+	fitted, report := budget.Fit(wordTokenizer{}, "", "", ctx)
+	if len(fitted.OldTranscripts) != 2 || fitted.OldTranscripts[0] != "B: b b b b" || fitted.OldTranscripts[1] != "C: c" {
+		t.Errorf("old transcripts were not capped correctly: %q", fitted.OldTranscripts)
+	}
+	if report.DroppedOldTranscripts != 2 || report.TruncatedOldTranscripts != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestBudgetFitKeepsLatestLine(t *testing.T) {
+	ctx := PromptContext{
+		OldTranscripts:    []string{"A: a a", "B: b b"},
+		CurrentTranscript: "A: old\nB: this line is very long",
+	}
+	fitted, report := PromptBudget{MaxTokens: 8}.Fit(wordTokenizer{}, "", "", ctx)
+	if fitted.CurrentTranscript != "B: this line is very long" {
+		t.Errorf("expected only the latest line to be kept but got:\n%s", fitted.CurrentTranscript)
+	}
+	if len(fitted.OldTranscripts) != 0 || report.DroppedOldTranscripts != 2 || report.DroppedLines != 1 {
+		t.Errorf("expected old transcripts to make room for the latest line but got %q and %+v", fitted.OldTranscripts, report)
+	}
+}
+
+func TestBudgetFitCapsLore(t *testing.T) {
+	ctx := PromptContext{
+		Lore:              []string{"most relevant lore", "less relevant lore here", "least"},
+		CurrentTranscript: "A: now",
+	}
+	fitted, report := PromptBudget{MaxTokens: 100, MaxLoreTokens: 5}.Fit(wordTokenizer{}, "", "", ctx)
+	if len(fitted.Lore) != 1 || fitted.Lore[0] != "most relevant lore" || report.DroppedLore != 2 {
+		t.Errorf("expected only the most relevant lore to be kept but got %q and %+v", fitted.Lore, report)
+	}
+
+	fitted, report = PromptBudget{MaxTokens: 4}.Fit(wordTokenizer{}, "", "", ctx)
+	if len(fitted.Lore) != 0 || fitted.CurrentTranscript != "A: now" || report.DroppedLore != 3 {
+		t.Errorf("expected lore to make room for the latest line but got %q and %+v", fitted.Lore, report)
+	}
+}
+
+func TestBudgetFitSubtractsSystemPrompt(t *testing.T) {
+	ctx := PromptContext{
+		CurrentTranscript: "A: one one one\nB: two two\nA: three\nB: four",
+	}
+	fitted, report := PromptBudget{MaxTokens: 12}.Fit(wordTokenizer{}, "", "Du bist Petra.", ctx)
+	if fitted.CurrentTranscript != "B: two two\nA: three\nB: four" {
+		t.Errorf("expected the oldest line to make room for the system prompt but got:\n%s", fitted.CurrentTranscript)
+	}
+	if report.Budget != 9 || report.DroppedLines != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestBudgetTokensFor(t *testing.T) {
+	tests := []struct {
+		budget   PromptBudget
+		model    string
+		expected int
+	}{
+		{PromptBudget{}, "", defaultPromptTokens},
+		{PromptBudget{}, "gpt-4o", defaultPromptTokens},
+		{PromptBudget{}, "gpt-4", 4096},
+		{PromptBudget{MaxTokens: 20000}, "gpt-4", 20000},
+	}
+	for _, test := range tests {
+		if actual := test.budget.TokensFor(test.model); actual != test.expected {
+			t.Errorf("expected budget %+v for model %q to allow %d tokens but got %d", test.budget, test.model, test.expected, actual)
+		}
+	}
+	if err := (PromptBudget{MaxOldTranscripts: -1}).Validate(); err == nil {
+		t.Error("expected negative budget to be invalid")
+	}
+}
@@ -4,15 +4,20 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"log/slog"
+	"maps"
 	"math/rand"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"text/template"
+	"time"
 
-	"github.com/MrWong99/TaileVoices/discord_bot/pkg/oai"
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/dice"
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/usage"
 	"github.com/MrWong99/TaileVoices/discord_bot/pkg/vecdb"
-	"github.com/sashabaranov/go-openai"
 	"gopkg.in/yaml.v3"
 
 	_ "embed"
@@ -33,32 +38,93 @@ func init() {
 	if err != nil {
 		panic(fmt.Errorf("could not parse STT init prompt template: %w", err))
 	}
-	sampleCampaign := Campaign{
-		Name: "Test Me",
-		Players: map[string]string{
-			"test": "Me",
-		},
-		Actors: []*Actor{
-			{
-				Name: "Foo",
-			},
-			{
-				Name: "Bar",
-			},
-		},
-	}
-	err = sttPromptTemplate.Execute(io.Discard, sampleCampaign)
-	if err != nil {
+	if err := checkSTTTemplate(sttPromptTemplate); err != nil {
 		panic(fmt.Errorf("STT init prompt template can not be executed: %w", err))
 	}
 }
 
+// finishTimeout is how long an interrupted response waits for the playback to report what has been spoken.
+const finishTimeout = time.Second
+
 // ActorResponse that will be passed to the campaigns output chan.
 type ActorResponse struct {
 	// Actor that spoke.
 	Actor Actor
-	// Text that the actor wants to say.
-	Text string
+	// Chunks of text that the actor wants to say. Every chunk is a sentence that can be spoken on its own.
+	// Chunks will be closed once the actor has finished its response or got interrupted.
+	Chunks <-chan string
+	state  *responseState
+}
+
+type responseState struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	playing    atomic.Bool
+	mu         sync.Mutex
+	spoken     []string
+	finished   chan struct{}
+	finishOnce sync.Once
+}
+
+func newActorResponse(ctx context.Context, actor Actor, chunks <-chan string) ActorResponse {
+	ctx, cancel := context.WithCancel(ctx)
+	return ActorResponse{
+		Actor:  actor,
+		Chunks: chunks,
+		state: &responseState{
+			ctx:      ctx,
+			cancel:   cancel,
+			finished: make(chan struct{}),
+		},
+	}
+}
+
+// Context of the response. It is done once the response got interrupted, so playback should stop immediately.
+func (r ActorResponse) Context() context.Context {
+	return r.state.ctx
+}
+
+// PlaybackStarted reports that the first sentence of the response is being played. Only then can players interrupt it by speaking.
+func (r ActorResponse) PlaybackStarted() {
+	r.state.playing.Store(true)
+}
+
+// Spoken reports that the sentence has been played completely.
+func (r ActorResponse) Spoken(sentence string) {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	r.state.spoken = append(r.state.spoken, sentence)
+}
+
+// SpokenPartially reports that playback of the sentence was stopped after the played duration.
+// The spoken part of the sentence is estimated from a typical speech rate.
+func (r ActorResponse) SpokenPartially(sentence string, played time.Duration) {
+	if words := spokenWords(sentence, played); words != "" {
+		r.Spoken(words)
+	}
+}
+
+// Finish MUST be called once all chunks have been played or dropped. Only then will the response be written to the transcript.
+func (r ActorResponse) Finish() {
+	r.state.finishOnce.Do(func() {
+		close(r.state.finished)
+	})
+}
+
+func (r ActorResponse) spokenText() string {
+	r.state.mu.Lock()
+	defer r.state.mu.Unlock()
+	return strings.Join(r.state.spoken, " ")
+}
+
+// Text waits until the actor has finished and returns all chunks joined together. Calls Finish afterwards.
+func (r ActorResponse) Text() string {
+	chunks := make([]string, 0)
+	for chunk := range r.Chunks {
+		chunks = append(chunks, chunk)
+	}
+	r.Finish()
+	return strings.Join(chunks, " ")
 }
 
 // Campaign wraps various actors together under one hood and manages who speaks and who doesn't.
@@ -67,20 +133,98 @@ type Campaign struct {
 	Name string `yaml:"name"`
 	// Players mapping between Discord user ID and actual ingame name.
 	Players map[string]string `yaml:"players"`
+	// Characters that players switched to for the running session by Discord user ID.
+	Characters map[string]string `yaml:"characters"`
 	// Actors involved in the current session.
 	Actors []*Actor `yaml:"actors"`
-	// CurrentSessionTranscript of the running session. Will be stored in the vector DB once Close() is called.
-	CurrentSessionTranscript string `yaml:"transcript"`
-	transcriptMu             *sync.Mutex
-	dbClient                 *vecdb.Client
-	actorResponses           chan ActorResponse
+	// Narrator that recaps the story at the start of a session. Optional, it never takes part in the conversation.
+	Narrator *Actor `yaml:"narrator"`
+	// Lore files with world knowledge like the setting, factions or places. Relevant parts will be given to the actors.
+	Lore []string `yaml:"lore"`
+	// Turns configures the policy that decides which actor speaks next.
+	Turns TurnPolicyConfig `yaml:"turnPolicy"`
+	// RollingSummary configures how older parts of the running session are condensed for the actors.
+	RollingSummary RollingSummaryConfig `yaml:"rollingSummary"`
+	// Interruption decides when actors stop speaking.
+	Interruption InterruptionConfig `yaml:"interruption"`
+	// Dialogue bounds the exchanges between actors.
+	Dialogue DialogueConfig `yaml:"dialogue"`
+	// Classifier tags the lines of the transcript as in-character, out-of-character or narration. Actors never respond to out-of-character lines.
+	Classifier ClassifierConfig `yaml:"classifier"`
+	// Glossary of words of the campaign like places, items or factions that the speech recognition tends to mishear.
+	Glossary []GlossaryTerm `yaml:"glossary"`
+	// Correction of the transcript after the session before it is stored.
+	Correction CorrectionConfig `yaml:"correction"`
+	// Scene that the session currently plays in. Prompt templates can use it.
+	Scene string `yaml:"scene"`
+	// Prompts that override the default prompt templates for this campaign.
+	Prompts Prompts `yaml:"prompts"`
+	// Transcript of the running session. Will be stored in the vector DB once Close() is called.
+	Transcript *Transcript `yaml:"transcript"`
+	// StorySoFar summarizes the first SummarizedLines of the transcript.
+	StorySoFar string `yaml:"storySoFar"`
+	// SummarizedLines of the transcript that are part of StorySoFar.
+	SummarizedLines int `yaml:"summarizedLines"`
+	// StartedAt is the start of the running session.
+	StartedAt time.Time `yaml:"startedAt"`
+	// State of the game that actors can read and write with their tools like prices or quest progress.
+	State map[string]string `yaml:"state"`
+	// Inventory of items that actors gave to players by the ingame name of the player.
+	Inventory       map[string]map[string]int `yaml:"inventory"`
+	castMu          *sync.RWMutex
+	prompts         *prompts
+	stateMu         *sync.Mutex
+	muted           map[string]bool
+	roller          *dice.Roller
+	transcriptMu    *sync.Mutex
+	corrected       string
+	summarizing     bool
+	summaryRetryAt  int
+	lastSpoken      map[string]int
+	turnPolicy      TurnPolicy
+	classifier      Classifier
+	turnMu          *sync.Mutex
+	dbClient        *vecdb.Client
+	chatModel       llm.ChatModel
+	ledger          *usage.Ledger
+	budget          usage.Budget
+	tokenizer       llm.Tokenizer
+	ctx             context.Context
+	cancel          context.CancelFunc
+	responders      *sync.WaitGroup
+	responsesMu     *sync.Mutex
+	activeResponses map[*responseState]struct{}
+	exchange        *exchange
+	chainResponses  []time.Time
+	journalMu       *sync.Mutex
+	journalPath     string
+	stopOnce        *sync.Once
+	actorResponses  chan ActorResponse
 }
 
 type tmpCampaign struct {
-	Name                     string            `yaml:"name"`
-	Players                  map[string]string `yaml:"players"`
-	Actors                   []*Actor          `yaml:"actors"`
-	CurrentSessionTranscript string            `yaml:"transcript"`
+	Name            string                    `yaml:"name"`
+	Players         map[string]string         `yaml:"players"`
+	Characters      map[string]string         `yaml:"characters,omitempty"`
+	Actors          []*Actor                  `yaml:"actors"`
+	Narrator        *Actor                    `yaml:"narrator,omitempty"`
+	Lore            []string                  `yaml:"lore,omitempty"`
+	Turns           TurnPolicyConfig          `yaml:"turnPolicy,omitempty"`
+	RollingSummary  RollingSummaryConfig      `yaml:"rollingSummary,omitempty"`
+	Interruption    InterruptionConfig        `yaml:"interruption,omitempty"`
+	Dialogue        DialogueConfig            `yaml:"dialogue,omitempty"`
+	Classifier      ClassifierConfig          `yaml:"classifier,omitempty"`
+	Glossary        []GlossaryTerm            `yaml:"glossary,omitempty"`
+	Correction      CorrectionConfig          `yaml:"correction,omitempty"`
+	Scene           string                    `yaml:"scene,omitempty"`
+	Prompts         Prompts                   `yaml:"prompts,omitempty"`
+	Transcript      *Transcript               `yaml:"transcript"`
+	StorySoFar      string                    `yaml:"storySoFar,omitempty"`
+	SummarizedLines int                       `yaml:"summarizedLines,omitempty"`
+	StartedAt       time.Time                 `yaml:"startedAt,omitempty"`
+	State           map[string]string         `yaml:"state,omitempty"`
+	Inventory       map[string]map[string]int `yaml:"inventory,omitempty"`
+	Muted           []string                  `yaml:"muted,omitempty"`
 }
 
 // UnmarshalYAML implements the unmarshalling including the required initialization.
@@ -91,50 +235,315 @@ func (c *Campaign) UnmarshalYAML(value *yaml.Node) error {
 		return err
 	}
 
+	for name := range tmpCampaign.Turns.Weights {
+		if !slices.ContainsFunc(tmpCampaign.Actors, func(a *Actor) bool { return a.Name == name }) {
+			return fmt.Errorf("turn policy has weight for unknown actor %q", name)
+		}
+	}
+	turnPolicy, err := tmpCampaign.Turns.Build(rand.New(rand.NewSource(time.Now().UnixNano())))
+	if err != nil {
+		return fmt.Errorf("invalid turn policy: %w", err)
+	}
+	if err := tmpCampaign.RollingSummary.Validate(); err != nil {
+		return err
+	}
+	if err := tmpCampaign.Interruption.Validate(); err != nil {
+		return err
+	}
+	if err := tmpCampaign.Dialogue.Validate(); err != nil {
+		return fmt.Errorf("invalid dialogue: %w", err)
+	}
+	classifier, err := tmpCampaign.Classifier.Build()
+	if err != nil {
+		return fmt.Errorf("invalid classifier: %w", err)
+	}
+	if err := tmpCampaign.Correction.Validate(); err != nil {
+		return err
+	}
+	prompts, err := tmpCampaign.Prompts.parse()
+	if err != nil {
+		return fmt.Errorf("invalid prompts: %w", err)
+	}
+	muted := make(map[string]bool, len(tmpCampaign.Muted))
+	for _, name := range tmpCampaign.Muted {
+		if !slices.ContainsFunc(tmpCampaign.Actors, func(a *Actor) bool { return a.Name == name }) {
+			return fmt.Errorf("unknown actor %q is muted", name)
+		}
+		muted[name] = true
+	}
+
 	c.Name = tmpCampaign.Name
 	c.Players = tmpCampaign.Players
+	c.Characters = tmpCampaign.Characters
 	c.Actors = tmpCampaign.Actors
-	c.CurrentSessionTranscript = tmpCampaign.CurrentSessionTranscript
+	c.Narrator = tmpCampaign.Narrator
+	c.Lore = tmpCampaign.Lore
+	c.Turns = tmpCampaign.Turns
+	c.RollingSummary = tmpCampaign.RollingSummary
+	c.Interruption = tmpCampaign.Interruption
+	c.Dialogue = tmpCampaign.Dialogue
+	c.Classifier = tmpCampaign.Classifier
+	c.classifier = classifier
+	c.Glossary = tmpCampaign.Glossary
+	c.Correction = tmpCampaign.Correction
+	c.Scene = tmpCampaign.Scene
+	c.Prompts = tmpCampaign.Prompts
+	c.prompts = prompts
+	if err := c.applyPrompts(); err != nil {
+		return err
+	}
+	c.Transcript = tmpCampaign.Transcript
+	if c.Transcript == nil {
+		c.Transcript = NewTranscript()
+	}
+	c.StorySoFar = tmpCampaign.StorySoFar
+	c.SummarizedLines = min(tmpCampaign.SummarizedLines, c.Transcript.Len())
+	c.StartedAt = tmpCampaign.StartedAt
+	if c.StartedAt.IsZero() {
+		c.StartedAt = time.Now()
+	}
+	c.State = tmpCampaign.State
+	c.Inventory = tmpCampaign.Inventory
+	c.castMu = &sync.RWMutex{}
+	c.stateMu = &sync.Mutex{}
+	c.muted = muted
+	c.roller = dice.NewRoller(time.Now().UnixNano())
 	c.dbClient = vecdb.DefaultClient()
+	c.chatModel = llm.DefaultModel()
+	c.ledger = usage.NewLedger()
+	c.ledger.StartSession(c.StartedAt)
+	c.budget = usage.DefaultBudget()
+	c.tokenizer = llm.ApproxTokenizer{}
 	c.transcriptMu = &sync.Mutex{}
+	c.lastSpoken = make(map[string]int)
+	c.turnPolicy = turnPolicy
+	c.turnMu = &sync.Mutex{}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.responders = &sync.WaitGroup{}
+	c.responsesMu = &sync.Mutex{}
+	c.activeResponses = make(map[*responseState]struct{})
+	c.journalMu = &sync.Mutex{}
+	c.stopOnce = &sync.Once{}
 	c.actorResponses = make(chan ActorResponse)
 	return nil
 }
 
+// MarshalYAML stores the campaign including the state of the running session, so CampaignFromYaml can restore it.
+func (c *Campaign) MarshalYAML() (any, error) {
+	c.castMu.RLock()
+	defer c.castMu.RUnlock()
+	c.transcriptMu.Lock()
+	defer c.transcriptMu.Unlock()
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	muted := make([]string, 0, len(c.muted))
+	for name := range c.muted {
+		muted = append(muted, name)
+	}
+	slices.Sort(muted)
+	inventory := make(map[string]map[string]int, len(c.Inventory))
+	for player, items := range c.Inventory {
+		inventory[player] = maps.Clone(items)
+	}
+	return tmpCampaign{
+		Name:            c.Name,
+		Players:         c.Players,
+		Characters:      c.Characters,
+		Actors:          c.Actors,
+		Narrator:        c.Narrator,
+		Lore:            c.Lore,
+		Turns:           c.Turns,
+		RollingSummary:  c.RollingSummary,
+		Interruption:    c.Interruption,
+		Dialogue:        c.Dialogue,
+		Classifier:      c.Classifier,
+		Glossary:        c.Glossary,
+		Correction:      c.Correction,
+		Scene:           c.Scene,
+		Prompts:         c.Prompts,
+		Transcript:      c.Transcript,
+		StorySoFar:      c.StorySoFar,
+		SummarizedLines: c.SummarizedLines,
+		StartedAt:       c.StartedAt,
+		State:           maps.Clone(c.State),
+		Inventory:       inventory,
+		Muted:           muted,
+	}, nil
+}
+
 // NewCampaign or just new session of an existing campaign. Call Close() to store the transcript.
 func NewCampaign(name string, actors []*Actor, dbClient *vecdb.Client) *Campaign {
+	ctx, cancel := context.WithCancel(context.Background())
+	startedAt := time.Now()
+	ledger := usage.NewLedger()
+	ledger.StartSession(startedAt)
 	return &Campaign{
-		Name:                     name,
-		Actors:                   actors,
-		CurrentSessionTranscript: "",
-		transcriptMu:             &sync.Mutex{},
-		dbClient:                 dbClient,
-		actorResponses:           make(chan ActorResponse),
+		Name:            name,
+		Actors:          actors,
+		Transcript:      NewTranscript(),
+		StartedAt:       startedAt,
+		castMu:          &sync.RWMutex{},
+		prompts:         defaultPrompts(),
+		stateMu:         &sync.Mutex{},
+		muted:           make(map[string]bool),
+		roller:          dice.NewRoller(time.Now().UnixNano()),
+		transcriptMu:    &sync.Mutex{},
+		lastSpoken:      make(map[string]int),
+		turnPolicy:      NewNamePolicy(rand.New(rand.NewSource(time.Now().UnixNano()))),
+		classifier:      HeuristicClassifier{},
+		turnMu:          &sync.Mutex{},
+		dbClient:        dbClient,
+		chatModel:       llm.DefaultModel(),
+		ledger:          ledger,
+		budget:          usage.DefaultBudget(),
+		tokenizer:       llm.ApproxTokenizer{},
+		ctx:             ctx,
+		cancel:          cancel,
+		responders:      &sync.WaitGroup{},
+		responsesMu:     &sync.Mutex{},
+		activeResponses: make(map[*responseState]struct{}),
+		journalMu:       &sync.Mutex{},
+		stopOnce:        &sync.Once{},
+		actorResponses:  make(chan ActorResponse),
 	}
 }
 
+// SetTokenizer that is used to fit prompts into the budget of actors. Defaults to llm.ApproxTokenizer.
+func (c *Campaign) SetTokenizer(tokenizer llm.Tokenizer) {
+	c.tokenizer = tokenizer
+}
+
+// SetTurnPolicy that decides which actor speaks next. Overrides the policy configured in the YAML.
+// The policy must be safe for concurrent use.
+func (c *Campaign) SetTurnPolicy(policy TurnPolicy) {
+	c.turnMu.Lock()
+	defer c.turnMu.Unlock()
+	c.turnPolicy = policy
+}
+
+// SetClassifier that tags the lines of the transcript. Overrides the classifier configured in the YAML.
+// Use nil to stop classifying.
+func (c *Campaign) SetClassifier(classifier Classifier) {
+	c.castMu.Lock()
+	defer c.castMu.Unlock()
+	c.classifier = classifier
+}
+
+// SetDiceRoller that is used for all rolls of this campaign. Use a fixed seed to make rolls reproducible.
+func (c *Campaign) SetDiceRoller(roller *dice.Roller) {
+	c.roller = roller
+}
+
+// Roll the dice notation with the dice roller of the campaign.
+func (c *Campaign) Roll(notation string) (dice.Result, error) {
+	return c.roller.Roll(notation)
+}
+
+// SetChatModel that actors and summaries of this campaign will use. Defaults to llm.DefaultModel().
+func (c *Campaign) SetChatModel(model llm.ChatModel) {
+	c.chatModel = model
+}
+
 // CampaignFromYaml read first YAML object from data as campaign and initialize all of the actors so the campaign is ready to receive messages.
 // The YAML data should be provided like this:
 //
 //	name: <name of the campaign>
+//	startedAt: <start of the running session. can be omitted>
 //	transcript: |-
-//	  <transcript of the current session. can be omitted>
-//	players:
-//	  player1discordn4me: Some Character
-//	  pl4yer2: Foo Name
-//	  lastpla1er: GameMaster
+//	  <transcript of the current session as "Name: text" lines. can be omitted
+//	  or be a list of entries with speakerID, name, start, end, source and text>
+//	players: # by Discord user ID. entries by username still work but are replaced on /player register
+//	  "123456789012345678": Some Character
+//	  "234567890123456789": Foo Name
+//	  "345678901234567890": GameMaster
+//	characters: # characters that players switched to for the running session. can be omitted
+//	  "234567890123456789": Bar Name
+//	lore: # markdown or text files with world knowledge. can be omitted
+//	  - <path to the first lore file>
 //	actors:
 //	  - name: <name of the first actor>
 //	    aliases: # can be omitted
 //	      - <first alias>
 //	      - <second alias>
 //	      - ...
-//		voice: <name of the OpenAI voice to use>
+//	    voice: <name of the OpenAI voice to use>
+//	    model: <chat model to use. can be omitted>
+//	    temperature: <0 to 2. can be omitted>
+//	    maxTokens: <maximum length of a response. can be omitted>
+//	    topP: <0 to 1. can be omitted>
+//	    presencePenalty: <-2 to 2. can be omitted>
+//	    stop: # up to 4 stop sequences. can be omitted
+//	      - <stop sequence>
+//	    budget: # limits the transcript text per response. can be omitted
+//	      maxTokens: <old transcripts plus current transcript. defaults to 6000>
+//	      maxOldTranscripts: <defaults to 4>
+//	      maxOldTranscriptTokens: <per old transcript. defaults to 500>
+//	      maxLoreTokens: <for all lore. defaults to 1000>
+//	    tools: # tools the actor may call. can be omitted
+//	      - <roll_dice, search_transcripts, get_state, set_state or give_item>
 //	    script: |-
 //	      <script that the first actor should follow
 //	      with multiple lines indented by 2 spaces after script:>
 //	  - name: <name of the second actor>
 //	    ...
+//	narrator: # recaps the story at the start of a session. can be omitted
+//	  name: <name of the narrator>
+//	  voice: <name of the OpenAI voice to use>
+//	  model: <chat model to use. can be omitted>
+//	  script: <style of the recap. can be omitted>
+//	state: # game state that actors can read and write with their tools. can be omitted
+//	  <key>: <value>
+//	inventory: # items that actors gave to players. can be omitted
+//	  <ingame name of a player>:
+//	    <item>: <quantity>
+//	muted: # actors that only speak when told to. can be omitted
+//	  - <name of an actor>
+//	scene: <scene that the session plays in for the prompt templates. can be omitted>
+//	prompts: # overrides of the default prompt templates. can be omitted
+//	  npcSystem: <inline template. executed with ActorPromptData>
+//	  npcUser: # or read from a file. executed with UserPromptData
+//	    file: <path to the template>
+//	  sttInit: <executed with the campaign>
+//	  summarization: <executed with CampaignPromptData>
+//	  recap: <system prompt of the narrator. executed with ActorPromptData>
+//	  correction: <instructs the transcript correction. executed with CampaignPromptData>
+//	rollingSummary: # condenses older lines of the running session for the actors. can be omitted
+//	  disabled: <true to always give actors the whole transcript>
+//	  threshold: <lines of transcript that trigger a summary. defaults to 150>
+//	  keep: <latest lines to keep verbatim. defaults to 50>
+//	  model: <chat model for the summary. can be omitted>
+//	interruption: # can be omitted
+//	  policy: <speech (default), stop-word or never>
+//	  minSpeech: <how long players speak before they interrupt. defaults to 600ms>
+//	  stopWords: # words that stop all actors. defaults to stop
+//	    - <first stop word>
+//	classifier: # tags lines as in-character, out-of-character or narration. can be omitted
+//	  type: <heuristic (default), llm or none>
+//	  model: <chat model for llm. can be omitted>
+//	  window: <transcript lines that llm considers. defaults to 10>
+//	  gameMaster: <ingame name of the game master. defaults to GameMaster>
+//	  prompts: <keep (default), mark or drop out-of-character lines for the actors>
+//	  storage: <keep (default), mark or drop out-of-character lines in the vector DB>
+//	  summaries: <keep (default), mark or drop out-of-character lines in summaries and recaps>
+//	glossary: # words of the campaign like places, items or factions. can be omitted
+//	  - <first word>
+//	  - term: <second word>
+//	    hints: # how the word sounds or is often misheard. can be omitted
+//	      - <first hint>
+//	correction: # corrects the transcript after the session. can be omitted
+//	  enabled: <true to let a LLM fix misheard names and merge fragmented lines>
+//	  model: <chat model for the correction. can be omitted>
+//	  chunkLines: <transcript lines that are corrected at once. defaults to 100>
+//	dialogue: # bounds the exchanges between actors. can be omitted
+//	  maxChain: <actor responses that may follow a single player line. defaults to 3>
+//	  perMinute: <actor responses to other actors within a minute. defaults to 6>
+//	turnPolicy: # can be omitted
+//	  type: <name (default), llm-judge, weighted-random or least-recent>
+//	  model: <chat model for llm-judge. can be omitted>
+//	  window: <transcript lines that llm-judge considers. defaults to 20>
+//	  chance: <0 to 1 chance that weighted-random lets an unaddressed actor speak>
+//	  weights: # weighted-random weights by actor name. defaults to 1
+//	    <name of the first actor>: 2
 func CampaignFromYaml(data []byte) (*Campaign, error) {
 	var c Campaign
 	return &c, yaml.Unmarshal(data, &c)
@@ -142,114 +551,298 @@ func CampaignFromYaml(data []byte) (*Campaign, error) {
 
 // C is the campaigns response channel. If any actor produced a response after HandleText was called it will be returned in C.
 // C is unbuffered and MUST be received. Also C will be closed once the campaign is closed.
+// Every response MUST be finished with ActorResponse.Finish once it was played and should stop playing once its context is done.
 //
 // The actor responses returned in C will automatically be fed into HandleText() so the caller of C SHOULD NOT call HandleText for the responses.
 func (c *Campaign) C() <-chan ActorResponse {
 	return c.actorResponses
 }
 
-// PlayerName of the player with given Discord user ID as registered in the campaign.
-func (c *Campaign) PlayerName(userID string) (name string, ok bool) {
-	name, ok = c.Players[userID]
-	return
-}
-
 // STTPrompt that should be fed to the STT context for better name recognition.
+// The glossary is ranked by the latest mention of its terms in this and earlier sessions. The terms that were mentioned
+// least recently are left out until the prompt fits into the prompt limit of Whisper. As Whisper cuts off overlong
+// prompts at their start, the glossary is given to the template with the most recently mentioned terms last.
 func (c *Campaign) STTPrompt() (string, error) {
-	promptBuf := bytes.NewBuffer(make([]byte, 0))
-	err := sttPromptTemplate.Execute(promptBuf, *c)
-	return promptBuf.String(), err
+	c.castMu.RLock()
+	glossary := c.Glossary
+	c.castMu.RUnlock()
+	var recent string
+	if len(glossary) > 0 {
+		recent = c.recentText()
+	}
+	c.castMu.RLock()
+	defer c.castMu.RUnlock()
+	ranked := rankGlossary(c.Glossary, recent)
+	data := *c
+	for {
+		data.Glossary = slices.Clone(ranked)
+		slices.Reverse(data.Glossary)
+		promptBuf := bytes.NewBuffer(make([]byte, 0))
+		if err := c.prompts.sttInit.Execute(promptBuf, data); err != nil {
+			return "", err
+		}
+		if len(ranked) == 0 || sttPromptTokens(promptBuf.String()) <= sttPromptTokenLimit {
+			if left := len(c.Glossary) - len(ranked); left > 0 {
+				slog.Info("glossary terms do not fit into the STT prompt", "campaign", c.Name, "terms", len(ranked), "leftOut", left)
+			}
+			return promptBuf.String(), nil
+		}
+		ranked = ranked[:len(ranked)-1]
+	}
 }
 
 // HandleText spoken by a person or NPC actor.
-func (c *Campaign) HandleText(name string, segment string) {
-	line := fmt.Sprintf("%s: %s", name, segment)
-	c.transcriptMu.Lock()
-	if c.CurrentSessionTranscript == "" {
-		c.CurrentSessionTranscript = line
+// Every player line starts a new exchange in which actors may respond to each other within the limits of the Dialogue config.
+// Out-of-character lines are only added to the transcript, unless they contain a stop word.
+func (c *Campaign) HandleText(entry TranscriptEntry) {
+	turn, index := c.appendEntry(entry)
+	entry = c.classify(index, entry)
+	if entry.Tag == TagOutOfCharacter && !c.Interruption.containsStopWord(entry.Text) {
+		return // Table talk is not meant for the actors
+	}
+	entries := c.Transcript.Entries()
+	turn.Transcript = c.Classifier.render(entries[:min(index+1, len(entries))], c.Classifier.Prompts)
+	var ex *exchange
+	if c.isActor(entry.Name) {
+		if entry.ChainDepth >= c.Dialogue.maxChain() {
+			slog.Info("exchange between actors reached its maximum length", "campaign", c.Name, "chainStart", entry.ChainStart, "depth", entry.ChainDepth)
+			return
+		}
+		if ex = c.currentExchange(entry); ex == nil {
+			return // Exchange was broken by the GM or replaced by a new player line
+		}
 	} else {
-		c.CurrentSessionTranscript += "\n" + line
+		ex = c.startExchange(index)
+		if c.Interruption.containsStopWord(entry.Text) {
+			ex.broken.Store(true)
+			if c.Interrupt() {
+				slog.Info("stop word interrupted actors", "campaign", c.Name, "speakerID", entry.SpeakerID)
+			}
+			return // Nobody should start talking right after being stopped
+		}
 	}
-	c.transcriptMu.Unlock()
 
-	involvedActors := make([]*Actor, 0)
-	for _, actor := range c.Actors {
-		// Actors should not respond to themselfes aswell as they must be included in the text.
-		if actor.Name == name || !actor.IsAdressed(segment) {
-			continue
+	for _, actor := range c.CurrentActors() {
+		// Actors should not respond to themselfes.
+		if actor.Name != entry.Name && !c.IsMuted(actor.Name) {
+			turn.Candidates = append(turn.Candidates, actor)
 		}
-		involvedActors = append(involvedActors, actor)
-	}
-	var nextActor *Actor
-	switch len(involvedActors) {
-	case 0:
-		return // No one involved, just update transcript
-	case 1:
-		nextActor = involvedActors[0]
-	default:
-		// Multiple actors involved. Roll one randomly to be the next
-		nextActor = involvedActors[rand.Intn(len(involvedActors))]
-	}
-
-	go func() {
-		promptContext := PromptContext{
-			CurrentTranscript: c.CurrentTranscript(),
-		}
-		oldTranscripts, err := c.dbClient.SearchTranscripts(c.Name, segment)
+	}
+	if len(turn.Candidates) == 0 {
+		return // No one can respond, just update transcript
+	}
+	if !c.addResponder() {
+		return // Campaign is closing, just update transcript
+	}
+	go c.respond(turn, entry.Text, ex, entry.ChainDepth)
+}
+
+// appendEntry to the transcript and return the turn that it starts without any candidates and transcript as well as the index of the entry.
+func (c *Campaign) appendEntry(entry TranscriptEntry) (Turn, int) {
+	c.transcriptMu.Lock()
+	defer c.transcriptMu.Unlock()
+	index := c.Transcript.Append(entry)
+	if c.isActor(entry.Name) {
+		c.lastSpoken[entry.Name] = index
+	}
+	c.summarizeIfNeeded()
+	return Turn{
+		Speaker:    entry.Name,
+		Segment:    entry.Text,
+		Candidates: make([]*Actor, 0),
+		LastSpoken: maps.Clone(c.lastSpoken),
+		Model:      c.model(UsageTurnPolicy),
+	}, index
+}
+
+func (c *Campaign) isActor(name string) bool {
+	c.castMu.RLock()
+	defer c.castMu.RUnlock()
+	return slices.ContainsFunc(c.Actors, func(a *Actor) bool { return a.Name == name })
+}
+
+// addResponder registers a new responding goroutine. Returns false if the campaign is closing.
+func (c *Campaign) addResponder() bool {
+	c.responsesMu.Lock()
+	defer c.responsesMu.Unlock()
+	if c.ctx.Err() != nil {
+		return false
+	}
+	c.responders.Add(1)
+	return true
+}
+
+// respond to the turn with the actor that the turn policy chooses, if any. depth is the chain depth of the line that is responded to.
+func (c *Campaign) respond(turn Turn, concept string, ex *exchange, depth int) {
+	defer c.responders.Done()
+	c.turnMu.Lock()
+	policy := c.turnPolicy
+	c.turnMu.Unlock()
+	// The turn is a snapshot of the session, so policies like the llm-judge don't hold up other lines while they decide.
+	nextActor, err := policy.NextActor(c.ctx, turn)
+	if err != nil {
+		slog.Warn("turn policy could not decide who speaks next", "campaign", c.Name, "error", err)
+		return
+	}
+	if nextActor == nil {
+		return // No one involved, transcript is already updated
+	}
+	if ex.broken.Load() || (depth > 0 && !c.reserveChainResponse(ex.start)) {
+		return
+	}
+	c.act(nextActor, concept, "", ex, depth)
+}
+
+// act lets the actor respond to the concept within the exchange, following the direction of the GM if it is not empty.
+// The response is added to the transcript once it was spoken.
+func (c *Campaign) act(nextActor *Actor, concept, direction string, ex *exchange, depth int) {
+	nextActor, ok := c.applyBudget(nextActor)
+	if !ok {
+		return
+	}
+
+	promptContext := c.currentPromptContext()
+	promptContext.Direction = direction
+	if c.dbClient != nil {
+		c.recordEmbedding(concept)
+		oldTranscripts, err := c.dbClient.SearchTranscripts(c.Name, concept)
 		if err != nil {
-			slog.Warn("could not search old transcripts for reference", "error", err, "collection", c.Name, "concept", segment)
+			slog.Warn("could not search old transcripts for reference", "error", err, "collection", c.Name, "concept", concept)
 		}
 		promptContext.OldTranscripts = oldTranscripts
+	}
+	promptContext.Lore = c.searchLore(concept)
+	model := llm.ResolveModel(c.chatModel, nextActor.Model)
+	promptContext, report := nextActor.Budget.Fit(c.tokenizer, model, nextActor.systemPrompt, promptContext)
+	if report.Dropped() {
+		slog.Info("prompt context exceeded budget of actor", "name", nextActor.Name, "budget", report.Budget, "tokens", report.Tokens,
+			"droppedLines", report.DroppedLines, "droppedOldTranscripts", report.DroppedOldTranscripts, "truncatedOldTranscripts", report.TruncatedOldTranscripts, "droppedLore", report.DroppedLore)
+	}
 
-		result, err := nextActor.Act(promptContext)
-		if err != nil {
-			slog.Error("actor had an error while responding", "name", nextActor.Name, "error", err)
-			result = "Sorry I wanted to say something but my brain just broke... Don't count on me right now!"
+	start := time.Now()
+	chunks := make(chan string, 32)
+	resp := newActorResponse(c.ctx, *nextActor, chunks)
+	c.trackResponse(resp.state)
+	defer c.untrackResponse(resp.state)
+	ctx := resp.Context()
+	select {
+	case c.actorResponses <- resp:
+	case <-ctx.Done():
+		return
+	}
+	spoken := 0
+	result, err := nextActor.ActStream(ctx, c.model(nextActor.Name), promptContext, campaignToolbox{c}, func(sentence string) {
+		select {
+		case chunks <- sentence:
+			spoken++
+		case <-ctx.Done():
 		}
-		c.actorResponses <- ActorResponse{
-			Actor: *nextActor,
-			Text:  result,
+	})
+	if err != nil && ctx.Err() == nil {
+		slog.Error("actor had an error while responding", "name", nextActor.Name, "error", err)
+		if spoken == 0 {
+			result = "Sorry I wanted to say something but my brain just broke... Don't count on me right now!"
+			chunks <- result
 		}
-		c.HandleText(nextActor.Name, result)
-	}()
-}
+	}
+	close(chunks)
 
-// Summary of the current campaign transcript. Excludes all non pen & paper related content.
-// Uses a GenAI to do the summary.
-//
-// Can be called after Close() has been called.
-func (c *Campaign) Summary() (string, error) {
-	resp, err := oai.Client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
-		Model: openai.GPT4o,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: summarizationPrompt,
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: c.CurrentTranscript(),
-			},
-		},
+	c.finishResponse(resp, TranscriptEntry{
+		SpeakerID:  nextActor.Name,
+		Name:       nextActor.Name,
+		Start:      start,
+		Source:     SourceNPC,
+		Text:       result,
+		ChainStart: ex.start,
+		ChainDepth: depth + 1,
 	})
-	if err != nil {
-		return "", fmt.Errorf("failed to create chat completion: %w", err)
+}
+
+// finishResponse waits until the response has been played and adds its entry to the transcript, so other actors may respond to it.
+// Interrupted responses are only added with the part that has been spoken.
+func (c *Campaign) finishResponse(resp ActorResponse, entry TranscriptEntry) {
+	ctx := resp.Context()
+	select {
+	case <-resp.state.finished:
+	case <-ctx.Done():
+		// Give the playback a moment to report how much has been spoken.
+		select {
+		case <-resp.state.finished:
+		case <-time.After(finishTimeout):
+		}
+		entry.Text = resp.spokenText()
+		entry.Interrupted = true
 	}
-	return resp.Choices[0].Message.Content, nil
+	entry.End = time.Now()
+	if entry.Interrupted {
+		if entry.Text != "" {
+			// Players are already talking again, so nobody should respond to the cut-off line.
+			_, index := c.appendEntry(entry)
+			c.classify(index, entry)
+		}
+		return
+	}
+	c.HandleText(entry)
+}
+
+func (c *Campaign) trackResponse(state *responseState) {
+	c.responsesMu.Lock()
+	defer c.responsesMu.Unlock()
+	c.activeResponses[state] = struct{}{}
+}
+
+func (c *Campaign) untrackResponse(state *responseState) {
+	c.responsesMu.Lock()
+	defer c.responsesMu.Unlock()
+	state.cancel()
+	delete(c.activeResponses, state)
 }
 
 // CurrentTranscript of this session.
 func (c *Campaign) CurrentTranscript() string {
-	c.transcriptMu.Lock()
-	defer c.transcriptMu.Unlock()
-	return c.CurrentSessionTranscript
+	return c.Transcript.String()
 }
 
 // Close this campaigns session by closing C() and storing its transcript in the vector database.
+// If a correction was accepted the original transcript is archived in the OriginalCollection.
+// The journal is kept if the transcript could not be stored, so the session can still be resumed.
 func (c *Campaign) Close() error {
-	close(c.actorResponses)
-	c.transcriptMu.Lock()
-	fmt.Printf("storing transcript for campaign %q\n%s\n", c.Name, c.CurrentSessionTranscript)
-	defer c.transcriptMu.Unlock()
-	return c.dbClient.StoreText(c.Name, c.CurrentSessionTranscript)
+	c.stop()
+	if err := c.FlushJournal(); err != nil {
+		slog.Warn("could not write journal", "campaign", c.Name, "error", err)
+	}
+	transcript, original := c.storedTranscript()
+	if original != "" {
+		// Stored as document of the session, so closing again after a failure replaces the archive instead of duplicating it
+		if err := c.dbClient.StoreDocument(c.OriginalCollection(), c.StartedAt.Format(time.RFC3339), original); err != nil {
+			return fmt.Errorf("could not archive original transcript: %w", err)
+		}
+	}
+	fmt.Printf("storing transcript for campaign %q\n%s\n", c.Name, transcript)
+	if err := c.dbClient.StoreText(c.Name, c.StartedAt.Format(time.RFC3339), transcript); err != nil {
+		return err
+	}
+	c.recordEmbedding(transcript)
+	if err := c.ledger.Save(); err != nil {
+		slog.Warn("could not save usage ledger", "campaign", c.Name, "error", err)
+	}
+	return c.removeJournal()
+}
+
+// Suspend this campaigns session by closing C() without storing its transcript.
+// The journal will be written a last time so the session can be resumed later.
+func (c *Campaign) Suspend() error {
+	c.stop()
+	return c.FlushJournal()
+}
+
+// stop all actors and close C(). Can be called multiple times.
+func (c *Campaign) stop() {
+	c.stopOnce.Do(func() {
+		c.responsesMu.Lock()
+		c.cancel()
+		c.responsesMu.Unlock()
+		c.responders.Wait()
+		close(c.actorResponses)
+	})
 }
@@ -0,0 +1,292 @@
+package pnp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+	"github.com/sashabaranov/go-openai"
+	"gopkg.in/yaml.v3"
+)
+
+const testCampaignYaml = `name: Test
+players:
+  "1234": Tharkhan
+actors:
+  - name: Petra Gabriel
+    aliases:
+      - Foxie
+    voice: nova
+    script: Du bist Petra.
+`
+
+// newChatStandIn answers every chat completion request with the given answer. Streamed requests get the answer word by word.
+// If received is not nil the last request will be stored in it.
+func newChatStandIn(t *testing.T, answer string, received *openai.ChatCompletionRequest) llm.ChatModel {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("could not decode chat completion request: %v", err)
+		}
+		if received != nil {
+			*received = req
+		}
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, word := range strings.SplitAfter(answer, " ") {
+				chunk, _ := json.Marshal(openai.ChatCompletionStreamResponse{
+					Choices: []openai.ChatCompletionStreamChoice{
+						{Delta: openai.ChatCompletionStreamChoiceDelta{Content: word}},
+					},
+				})
+				fmt.Fprintf(w, "data: %s\n\n", chunk)
+			}
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Model: req.Model,
+			Choices: []openai.ChatCompletionChoice{
+				{
+					Message: openai.ChatCompletionMessage{
+						Role:    openai.ChatMessageRoleAssistant,
+						Content: answer,
+					},
+				},
+			},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	model, err := llm.NewOpenAICompatible(srv.URL+"/v1", "", "test-model")
+	if err != nil {
+		t.Fatalf("could not create chat model: %v", err)
+	}
+	return model
+}
+
+func testCampaign(t *testing.T, answer string) *Campaign {
+	t.Helper()
+	return campaignFromYaml(t, "", answer, nil)
+}
+
+// campaignFromYaml reads testCampaignYaml with the top level keys of delta added or replaced and lets it talk to newChatStandIn.
+func campaignFromYaml(t *testing.T, delta, answer string, received *openai.ChatCompletionRequest) *Campaign {
+	t.Helper()
+	c, err := CampaignFromYaml([]byte(testCampaignYamlWith(t, delta)))
+	if err != nil {
+		t.Fatalf("could not read test campaign: %v", err)
+	}
+	c.SetChatModel(newChatStandIn(t, answer, received))
+	return c
+}
+
+// testCampaignYamlWith returns testCampaignYaml with the top level keys of delta added or replaced.
+func testCampaignYamlWith(t *testing.T, delta string) string {
+	t.Helper()
+	var base, changes yaml.Node
+	if err := yaml.Unmarshal([]byte(testCampaignYaml), &base); err != nil {
+		t.Fatalf("could not parse test campaign: %v", err)
+	}
+	if err := yaml.Unmarshal([]byte(delta), &changes); err != nil {
+		t.Fatalf("could not parse campaign delta: %v", err)
+	}
+	if len(changes.Content) == 0 {
+		return testCampaignYaml
+	}
+	fields := base.Content[0]
+	newFields := changes.Content[0].Content
+	for i := 0; i < len(newFields); i += 2 {
+		replaced := false
+		for j := 0; j < len(fields.Content); j += 2 {
+			if fields.Content[j].Value == newFields[i].Value {
+				fields.Content[j+1] = newFields[i+1]
+				replaced = true
+			}
+		}
+		if !replaced {
+			fields.Content = append(fields.Content, newFields[i], newFields[i+1])
+		}
+	}
+	data, err := yaml.Marshal(&base)
+	if err != nil {
+		t.Fatalf("could not render test campaign: %v", err)
+	}
+	return string(data)
+}
+
+func TestHandleTextActorResponds(t *testing.T) {
+	c := testCampaign(t, "Zum Sonnenturm natürlich! Komm mit.")
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie, wo willst du hin?"})
+
+	select {
+	case resp := <-c.C():
+		if resp.Actor.Name != "Petra Gabriel" {
+			t.Errorf("expected Petra Gabriel to respond but got %s", resp.Actor.Name)
+		}
+		chunks := make([]string, 0)
+		for chunk := range resp.Chunks {
+			chunks = append(chunks, chunk)
+			resp.Spoken(chunk)
+		}
+		resp.Finish()
+		if len(chunks) != 2 || chunks[0] != "Zum Sonnenturm natürlich!" || chunks[1] != "Komm mit." {
+			t.Errorf("expected the response to be streamed sentence by sentence but got %q", chunks)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("actor did not respond in time")
+	}
+
+	expected := "Tharkhan: Foxie, wo willst du hin?\nPetra Gabriel: Zum Sonnenturm natürlich! Komm mit."
+	deadline := time.Now().Add(5 * time.Second)
+	for c.CurrentTranscript() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected transcript to be:\n%s\n\nbut got:\n%s", expected, c.CurrentTranscript())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	response := c.Transcript.Entries()[1]
+	if response.Source != SourceNPC || response.SpeakerID != "Petra Gabriel" || response.Start.IsZero() || response.End.Before(response.Start) {
+		t.Errorf("actor response entry is missing metadata: %+v", response)
+	}
+}
+
+func TestHandleTextNobodyAddressed(t *testing.T) {
+	c := testCampaign(t, "I should not be asked")
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Wo sind wir eigentlich gerade?"})
+
+	select {
+	case resp := <-c.C():
+		t.Errorf("no actor should respond but %s said %q", resp.Actor.Name, resp.Text())
+	case <-time.After(100 * time.Millisecond):
+	}
+	if !strings.HasPrefix(c.CurrentTranscript(), "Tharkhan: Wo sind") {
+		t.Errorf("line was not added to transcript: %q", c.CurrentTranscript())
+	}
+}
+
+func TestPlayerSpeakingInterruptsActor(t *testing.T) {
+	c := testCampaign(t, "Zum Sonnenturm natürlich! Komm mit, schnell.")
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie, wo willst du hin?"})
+
+	select {
+	case resp := <-c.C():
+		first, second := <-resp.Chunks, <-resp.Chunks
+		resp.PlaybackStarted()
+		resp.Spoken(first)
+		c.PlayerSpeaking("1234", time.Second)
+		select {
+		case <-resp.Context().Done():
+		case <-time.After(time.Second):
+			t.Fatal("response was not interrupted")
+		}
+		resp.SpokenPartially(second, 800*time.Millisecond)
+		resp.Finish()
+	case <-time.After(5 * time.Second):
+		t.Fatal("actor did not respond in time")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Transcript.Len() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("interrupted response was not added to the transcript")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	response := c.Transcript.Entries()[1]
+	if !response.Interrupted || response.Text != "Zum Sonnenturm natürlich! Komm mit," {
+		t.Errorf("expected only the spoken text to be recorded as interrupted but got %+v", response)
+	}
+	if response.String() != "Petra Gabriel: Zum Sonnenturm natürlich! Komm mit,—" {
+		t.Errorf("unexpected rendering of interrupted line %q", response.String())
+	}
+}
+
+func TestPlayerSpeakingKeepsResponse(t *testing.T) {
+	c := testCampaign(t, "Zum Sonnenturm natürlich!")
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie, wo willst du hin?"})
+
+	select {
+	case resp := <-c.C():
+		c.PlayerSpeaking("1234", time.Second)
+		if resp.Context().Err() != nil {
+			t.Fatal("response was interrupted before its playback started")
+		}
+		resp.PlaybackStarted()
+		c.PlayerSpeaking("1234", 100*time.Millisecond)
+		if resp.Context().Err() != nil {
+			t.Fatal("response was interrupted by a short noise")
+		}
+		if text := resp.Text(); text != "Zum Sonnenturm natürlich!" {
+			t.Errorf("unexpected response %q", text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("actor did not respond in time")
+	}
+}
+
+func TestStopWordPreventsResponse(t *testing.T) {
+	c := testCampaign(t, "I should not be asked")
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie, STOP!"})
+
+	select {
+	case resp := <-c.C():
+		t.Errorf("no actor should respond to a stop word but %s said %q", resp.Actor.Name, resp.Text())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestInterruptionConfigValidate(t *testing.T) {
+	if err := (InterruptionConfig{Policy: "sometimes"}).Validate(); err == nil {
+		t.Error("expected unknown policy to be invalid")
+	}
+	if err := (InterruptionConfig{StopWords: []string{" "}}).Validate(); err == nil {
+		t.Error("expected empty stop word to be invalid")
+	}
+	if err := (InterruptionConfig{MinSpeech: -time.Second}).Validate(); err == nil {
+		t.Error("expected negative minimum speech to be invalid")
+	}
+	var cfg InterruptionConfig
+	if err := yaml.Unmarshal([]byte("minSpeech: 1.5s"), &cfg); err != nil || cfg.MinSpeech != 1500*time.Millisecond {
+		t.Errorf("expected minimum speech of 1.5s but got %v (error: %v)", cfg.MinSpeech, err)
+	}
+	if (InterruptionConfig{Policy: InterruptNever}).containsStopWord("stop") {
+		t.Error("stop words should be ignored if actors are never interrupted")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	c := testCampaign(t, `{"scenes": ["Die Helden suchen den Sonnenturm."], "npcs": [{"name": "Petra", "description": "Eine Händlerin."}], "openThreads": ["Wo ist der Sonnenturm?"]}`)
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Wo ist der Sonnenturm?"})
+
+	summary, err := c.Summary()
+	if err != nil {
+		t.Fatalf("unexpected error during summary: %v", err)
+	}
+	if len(summary.Scenes) != 1 || summary.Scenes[0] != "Die Helden suchen den Sonnenturm." {
+		t.Errorf("unexpected scenes %q", summary.Scenes)
+	}
+	if summary.Campaign != c.Name || !summary.StartedAt.Equal(c.StartedAt) {
+		t.Errorf("expected summary to be linked to the session but got %q %v", summary.Campaign, summary.StartedAt)
+	}
+	if text := summary.String(); !strings.Contains(text, "## NPCs\n- Petra: Eine Händlerin.\n") || strings.Contains(text, "Locations") {
+		t.Errorf("unexpected rendering of summary:\n%s", text)
+	}
+}
+
+func TestSummaryInUnexpectedFormat(t *testing.T) {
+	c := testCampaign(t, "1. Die Helden suchen den Sonnenturm.")
+	if _, err := c.Summary(); err == nil {
+		t.Error("expected a summary that is no JSON object to be rejected")
+	}
+}
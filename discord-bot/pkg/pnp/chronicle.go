@@ -0,0 +1,142 @@
+package pnp
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+)
+
+//go:embed session_summary_format.txt
+var sessionSummaryFormat string
+
+// SessionSummary is the structured summary of a session that is kept in the chronicle of the campaign.
+type SessionSummary struct {
+	// Campaign that the session belongs to.
+	Campaign string `json:"campaign"`
+	// StartedAt identifies the session.
+	StartedAt time.Time `json:"startedAt"`
+	// Scenes in the order they happened.
+	Scenes []string `json:"scenes"`
+	// NPCs that the players encountered.
+	NPCs []NPCEncounter `json:"npcs"`
+	// Locations that were visited or talked about.
+	Locations []string `json:"locations"`
+	// ItemsGained by the characters.
+	ItemsGained []ItemChange `json:"itemsGained"`
+	// ItemsLost by the characters.
+	ItemsLost []ItemChange `json:"itemsLost"`
+	// OpenThreads like quests or plans that are still open.
+	OpenThreads []string `json:"openThreads"`
+	// Quotes that are worth remembering.
+	Quotes []Quote `json:"quotes"`
+}
+
+// NPCEncounter of the players with a non-player character.
+type NPCEncounter struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// ItemChange of a character like a gained sword or lost gold.
+type ItemChange struct {
+	Item string `json:"item"`
+	Who  string `json:"who"`
+}
+
+// Quote of a character.
+type Quote struct {
+	Speaker string `json:"speaker"`
+	Text    string `json:"text"`
+}
+
+// String renders the summary as markdown. This is also the text that is stored in the chronicle.
+func (s SessionSummary) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s session of %s\n", s.Campaign, s.StartedAt.Format("2006-01-02 15:04"))
+	writeSection(&sb, "Scenes", s.Scenes, func(scene string) string { return scene })
+	writeSection(&sb, "NPCs", s.NPCs, func(npc NPCEncounter) string { return npc.Name + ": " + npc.Description })
+	writeSection(&sb, "Locations", s.Locations, func(location string) string { return location })
+	writeSection(&sb, "Items gained", s.ItemsGained, func(change ItemChange) string { return change.Who + ": " + change.Item })
+	writeSection(&sb, "Items lost", s.ItemsLost, func(change ItemChange) string { return change.Who + ": " + change.Item })
+	writeSection(&sb, "Open threads", s.OpenThreads, func(thread string) string { return thread })
+	writeSection(&sb, "Quotes", s.Quotes, func(quote Quote) string { return fmt.Sprintf("%s: %q", quote.Speaker, quote.Text) })
+	return sb.String()
+}
+
+func writeSection[T any](sb *strings.Builder, title string, entries []T, render func(T) string) {
+	if len(entries) == 0 {
+		return
+	}
+	fmt.Fprintf(sb, "\n## %s\n", title)
+	for _, entry := range entries {
+		fmt.Fprintf(sb, "- %s\n", render(entry))
+	}
+}
+
+// ChronicleCollection is the name of the vector DB collection that holds the summaries of all sessions of the campaign.
+func (c *Campaign) ChronicleCollection() string {
+	return c.Name + "Chronicle"
+}
+
+// Summary of the current session with scenes, NPCs, loot and open threads. Excludes all non pen & paper related content.
+// Uses a GenAI to do the summary. The summary is stored in the chronicle of the campaign in the vector DB, replacing
+// an earlier summary of the same session. Summarizes the corrected transcript if a correction was accepted.
+//
+// Can be called after Close() has been called.
+func (c *Campaign) Summary() (SessionSummary, error) {
+	c.castMu.RLock()
+	promptBuf := bytes.NewBuffer(make([]byte, 0))
+	err := c.prompts.summarization.Execute(promptBuf, c.promptData())
+	c.castMu.RUnlock()
+	if err != nil {
+		return SessionSummary{}, fmt.Errorf("could not resolve summarization prompt template: %w", err)
+	}
+	resp, err := c.model(UsageSummary).Chat(context.Background(), llm.Request{
+		Messages: []llm.Message{
+			{
+				Role:    llm.RoleSystem,
+				Content: promptBuf.String() + "\n\n" + sessionSummaryFormat,
+			},
+			{
+				Role:    llm.RoleUser,
+				Content: c.summarizedTranscript(),
+			},
+		},
+		JSON: true,
+	})
+	if err != nil {
+		return SessionSummary{}, err
+	}
+	var summary SessionSummary
+	if err := json.Unmarshal([]byte(resp.Content), &summary); err != nil {
+		return SessionSummary{}, fmt.Errorf("summary is not in the expected format: %w", err)
+	}
+	summary.Campaign = c.Name
+	summary.StartedAt = c.StartedAt
+
+	if c.dbClient != nil {
+		text := summary.String()
+		if err := c.dbClient.StoreDocument(c.ChronicleCollection(), c.StartedAt.Format(time.RFC3339), text); err != nil {
+			slog.Warn("could not store summary in chronicle", "campaign", c.Name, "error", err)
+		} else {
+			c.recordEmbedding(text)
+		}
+	}
+	return summary, nil
+}
+
+// summarizedTranscript is the text that Summary summarizes. This is the transcript that Close stores if a correction
+// was accepted, so the summary uses the corrected names too.
+func (c *Campaign) summarizedTranscript() string {
+	if transcript, original := c.storedTranscript(); original != "" {
+		return transcript
+	}
+	return c.Classifier.render(c.Transcript.Entries(), c.Classifier.Summaries)
+}
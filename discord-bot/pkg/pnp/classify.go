@@ -0,0 +1,288 @@
+package pnp
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+)
+
+//go:embed classify_prompt.tpl
+var classifyPromptText string
+
+var classifyPromptTemplate *template.Template
+
+func init() {
+	var err error
+	// Check if the classifier prompt template can be resolved.
+	classifyPromptTemplate, err = template.New("classify").Parse(classifyPromptText)
+	if err != nil {
+		panic(fmt.Errorf("could not parse classifier prompt template: %w", err))
+	}
+	if err := classifyPromptTemplate.Execute(io.Discard, LLMClassifier{GameMaster: defaultGameMaster}); err != nil {
+		panic(fmt.Errorf("classifier prompt template can not be executed: %w", err))
+	}
+}
+
+// Tag of a transcript line that tells how it belongs to the role-play.
+type Tag string
+
+const (
+	// TagInCharacter for lines that are spoken as a character, including all actor responses.
+	TagInCharacter Tag = "ic"
+	// TagOutOfCharacter for table talk like rules discussions or snacks.
+	TagOutOfCharacter Tag = "ooc"
+	// TagNarration for narration of the game master.
+	TagNarration Tag = "gm"
+)
+
+// defaultGameMaster is the ingame name of the game master if the classifier config has none.
+const defaultGameMaster = "GameMaster"
+
+// ClassifiedLine that a Classifier has to tag.
+type ClassifiedLine struct {
+	// Entry of the line.
+	Entry TranscriptEntry
+	// GameMaster is true if the game master said the line.
+	GameMaster bool
+	// Transcript of the current session including the line.
+	Transcript string
+	// Model of the campaign for classifiers that need to ask a LLM.
+	Model llm.ChatModel
+}
+
+// Classifier tags the lines of a session as they are added to the transcript.
+type Classifier interface {
+	// Classify the line.
+	Classify(ctx context.Context, line ClassifiedLine) (Tag, error)
+}
+
+// Names of the available classifiers as used in the campaign YAML.
+const (
+	ClassifierHeuristic = "heuristic"
+	ClassifierLLM       = "llm"
+	ClassifierNone      = "none"
+)
+
+// How consumers of the transcript treat out-of-character lines.
+const (
+	// OutOfCharacterKeep renders them like all other lines.
+	OutOfCharacterKeep = "keep"
+	// OutOfCharacterMark renders them as "Name (ooc): Text", so a LLM can tell them apart.
+	OutOfCharacterMark = "mark"
+	// OutOfCharacterDrop leaves them out.
+	OutOfCharacterDrop = "drop"
+)
+
+// ClassifierConfig selects and configures the Classifier of a campaign and how the tags are used.
+type ClassifierConfig struct {
+	// Type of the classifier. One of heuristic (default), llm or none.
+	// The llm classifier asks the model before actors can respond to a line, so it adds some latency.
+	Type string `yaml:"type"`
+	// Model for the llm classifier. Defaults to the model of the campaign.
+	Model string `yaml:"model"`
+	// Window of transcript lines that the llm classifier will see. Defaults to 10.
+	Window int `yaml:"window"`
+	// GameMaster is the ingame name of the game master as mapped in the players. Defaults to GameMaster.
+	GameMaster string `yaml:"gameMaster"`
+	// Prompts decides how out-of-character lines are given to actors. One of keep (default), mark or drop.
+	Prompts string `yaml:"prompts"`
+	// Storage decides how out-of-character lines are stored in the vector DB. One of keep (default), mark or drop.
+	Storage string `yaml:"storage"`
+	// Summaries decides how out-of-character lines are summarized. One of keep (default), mark or drop.
+	Summaries string `yaml:"summaries"`
+}
+
+// Validate that the config describes a valid classifier.
+func (cfg ClassifierConfig) Validate() error {
+	var err error
+	switch cfg.Type {
+	case "", ClassifierHeuristic, ClassifierLLM, ClassifierNone:
+	default:
+		err = errors.Join(err, fmt.Errorf("unknown classifier type %q", cfg.Type))
+	}
+	if cfg.Window < 0 {
+		err = errors.Join(err, fmt.Errorf("window must not be negative but is %d", cfg.Window))
+	}
+	for name, handling := range map[string]string{"prompts": cfg.Prompts, "storage": cfg.Storage, "summaries": cfg.Summaries} {
+		switch handling {
+		case "", OutOfCharacterKeep, OutOfCharacterMark, OutOfCharacterDrop:
+		default:
+			err = errors.Join(err, fmt.Errorf("%s must be keep, mark or drop but is %q", name, handling))
+		}
+	}
+	return err
+}
+
+// Build the configured classifier. Returns nil for the type none.
+func (cfg ClassifierConfig) Build() (Classifier, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	switch cfg.Type {
+	case ClassifierNone:
+		return nil, nil
+	case ClassifierLLM:
+		return &LLMClassifier{
+			Model:      cfg.Model,
+			Window:     cfg.Window,
+			GameMaster: cfg.gameMaster(),
+			Fallback:   HeuristicClassifier{},
+		}, nil
+	default:
+		return HeuristicClassifier{}, nil
+	}
+}
+
+func (cfg ClassifierConfig) gameMaster() string {
+	if cfg.GameMaster == "" {
+		return defaultGameMaster
+	}
+	return cfg.GameMaster
+}
+
+// render the entries with their out-of-character lines treated as the handling says.
+func (cfg ClassifierConfig) render(entries []TranscriptEntry, handling string) string {
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Tag == TagOutOfCharacter {
+			switch handling {
+			case OutOfCharacterDrop:
+				continue
+			case OutOfCharacterMark:
+				entry.Name += " (ooc)"
+			}
+		}
+		lines = append(lines, entry.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HeuristicClassifier tags lines of the game master as narration and lines in parentheses or starting with "ooc" or "//"
+// as out-of-character. Everything else is in-character.
+type HeuristicClassifier struct{}
+
+// Classify implements Classifier.
+func (HeuristicClassifier) Classify(_ context.Context, line ClassifiedLine) (Tag, error) {
+	if line.Entry.Source == SourceNPC || line.Entry.Source == SourceTool || line.Entry.Source == SourceDice {
+		return TagInCharacter, nil
+	}
+	text := strings.TrimSpace(line.Entry.Text)
+	if (strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")")) || strings.HasPrefix(strings.ToLower(text), "ooc") || strings.HasPrefix(text, "//") {
+		return TagOutOfCharacter, nil
+	}
+	if line.GameMaster {
+		return TagNarration, nil
+	}
+	return TagInCharacter, nil
+}
+
+// LLMClassifier asks a LLM how the line belongs to the role-play based on the recent transcript.
+// Actor responses, tool calls and dice rolls are always in-character.
+type LLMClassifier struct {
+	// Model to use instead of the default model of the lines chat model.
+	Model string
+	// Window of the most recent transcript lines that will be judged. Defaults to 10.
+	Window int
+	// GameMaster is the ingame name of the game master.
+	GameMaster string
+	// Fallback that decides if the LLM could not be asked. Can be nil.
+	Fallback Classifier
+}
+
+// Classify implements Classifier.
+func (c *LLMClassifier) Classify(ctx context.Context, line ClassifiedLine) (Tag, error) {
+	tag, err := c.classify(ctx, line)
+	if err != nil && c.Fallback != nil {
+		return c.Fallback.Classify(ctx, line)
+	}
+	return tag, err
+}
+
+func (c *LLMClassifier) classify(ctx context.Context, line ClassifiedLine) (Tag, error) {
+	if line.Entry.Source == SourceNPC || line.Entry.Source == SourceTool || line.Entry.Source == SourceDice {
+		return TagInCharacter, nil
+	}
+	if line.Model == nil {
+		return "", errors.New("no chat model available to classify the line")
+	}
+	systemPromptBuf := bytes.NewBuffer(make([]byte, 0))
+	if err := classifyPromptTemplate.Execute(systemPromptBuf, c); err != nil {
+		return "", fmt.Errorf("could not resolve classifier prompt template: %w", err)
+	}
+	window := c.Window
+	if window == 0 {
+		window = 10
+	}
+	lines := strings.Split(line.Transcript, "\n")
+	if len(lines) > window {
+		lines = lines[len(lines)-window:]
+	}
+
+	resp, err := line.Model.Chat(ctx, llm.Request{
+		Model:     c.Model,
+		MaxTokens: 5,
+		Messages: []llm.Message{
+			{
+				Role:    llm.RoleSystem,
+				Content: systemPromptBuf.String(),
+			},
+			{
+				Role:    llm.RoleUser,
+				Content: strings.Join(lines, "\n"),
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToLower(removeNonWordRunes(strings.TrimSpace(resp.Content))) {
+	case "ic":
+		return TagInCharacter, nil
+	case "ooc":
+		return TagOutOfCharacter, nil
+	case "gm":
+		return TagNarration, nil
+	default:
+		return "", fmt.Errorf("classifier answered with unknown tag %q", resp.Content)
+	}
+}
+
+// classifyTimeout bounds how long a line waits for the classifier. The line stays untagged if it takes longer.
+const classifyTimeout = 5 * time.Second
+
+// classify the entry at index of the transcript with the classifier of the campaign if it has no tag yet.
+// The entry is already part of the transcript, so lines are kept in the order in which they were spoken even if
+// classifying some of them takes longer. Returns the entry with its tag.
+func (c *Campaign) classify(index int, entry TranscriptEntry) TranscriptEntry {
+	c.castMu.RLock()
+	classifier := c.classifier
+	c.castMu.RUnlock()
+	if classifier == nil || entry.Tag != "" {
+		return entry
+	}
+	entries := c.Transcript.Entries()
+	ctx, cancel := context.WithTimeout(c.ctx, classifyTimeout)
+	defer cancel()
+	tag, err := classifier.Classify(ctx, ClassifiedLine{
+		Entry:      entry,
+		GameMaster: strings.EqualFold(entry.Name, c.Classifier.gameMaster()),
+		Transcript: renderEntries(entries[:index+1]),
+		Model:      c.model(UsageClassifier),
+	})
+	if err != nil {
+		slog.Warn("could not classify line", "campaign", c.Name, "speakerID", entry.SpeakerID, "error", err)
+		return entry
+	}
+	c.Transcript.SetTag(index, tag)
+	entry.Tag = tag
+	return entry
+}
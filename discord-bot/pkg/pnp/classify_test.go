@@ -0,0 +1,165 @@
+package pnp
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestHeuristicClassifier(t *testing.T) {
+	tests := map[string]struct {
+		line     ClassifiedLine
+		expected Tag
+	}{
+		"player":          {ClassifiedLine{Entry: TranscriptEntry{Name: "Tharkhan", Text: "Ich ziehe mein Schwert."}}, TagInCharacter},
+		"parentheses":     {ClassifiedLine{Entry: TranscriptEntry{Name: "Tharkhan", Text: " (Reich mal die Chips rüber.) "}}, TagOutOfCharacter},
+		"ooc prefix":      {ClassifiedLine{Entry: TranscriptEntry{Name: "Tharkhan", Text: "OOC: wann machen wir Pause?"}}, TagOutOfCharacter},
+		"game master":     {ClassifiedLine{Entry: TranscriptEntry{Name: "GameMaster", Text: "Die Tür knarzt."}, GameMaster: true}, TagNarration},
+		"game master ooc": {ClassifiedLine{Entry: TranscriptEntry{Name: "GameMaster", Text: "// Regelfrage: zählt das als Aktion?"}, GameMaster: true}, TagOutOfCharacter},
+		"actor":           {ClassifiedLine{Entry: TranscriptEntry{Name: "Brom", Source: SourceNPC, Text: "(flüstert) Kommt näher."}}, TagInCharacter},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tag, err := HeuristicClassifier{}.Classify(context.Background(), test.line)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tag != test.expected {
+				t.Errorf("expected %q but got %q", test.expected, tag)
+			}
+		})
+	}
+}
+
+const classifierCampaignYaml = `players:
+  "1234": Tharkhan
+  "5678": Meister
+classifier:
+  type: llm
+  model: gpt-4o-mini
+  gameMaster: Meister
+  prompts: drop
+  summaries: mark
+`
+
+func TestLLMClassifierTagsLines(t *testing.T) {
+	var received openai.ChatCompletionRequest
+	c := campaignFromYaml(t, classifierCampaignYaml, "OOC", &received)
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Wer will noch Pizza?"})
+	entries := c.Transcript.Entries()
+	if len(entries) != 1 || entries[0].Tag != TagOutOfCharacter {
+		t.Fatalf("expected the line to be tagged out-of-character but got %+v", entries)
+	}
+	if received.Model != "gpt-4o-mini" || !strings.Contains(received.Messages[0].Content, "game master Meister") {
+		t.Errorf("unexpected classifier request %+v", received)
+	}
+	if user := received.Messages[1].Content; user != "Tharkhan: Wer will noch Pizza?" {
+		t.Errorf("expected the transcript including the line but got:\n%s", user)
+	}
+
+	c.SetChatModel(newChatStandIn(t, "was?", nil))
+	c.HandleText(TranscriptEntry{SpeakerID: "5678", Name: "Meister", Source: SourceSTT, Text: "Die Tür knarzt."})
+	if entries := c.Transcript.Entries(); entries[1].Tag != TagNarration {
+		t.Errorf("expected the heuristic to tag the game master if the answer is unknown but got %q", entries[1].Tag)
+	}
+
+	if transcript := c.currentPromptContext().CurrentTranscript; transcript != "Meister: Die Tür knarzt." {
+		t.Errorf("expected out-of-character lines to be dropped from prompts but got:\n%s", transcript)
+	}
+	c.SetChatModel(newChatStandIn(t, "{}", &received))
+	if _, err := c.Summary(); err != nil {
+		t.Fatalf("could not summarize: %v", err)
+	}
+	if user := received.Messages[1].Content; user != "Tharkhan (ooc): Wer will noch Pizza?\nMeister: Die Tür knarzt." {
+		t.Errorf("expected out-of-character lines to be marked in summaries but got:\n%s", user)
+	}
+}
+
+// turnRecorder sends every turn to the channel and lets nobody respond.
+type turnRecorder chan Turn
+
+func (r turnRecorder) NextActor(_ context.Context, turn Turn) (*Actor, error) {
+	r <- turn
+	return nil, nil
+}
+
+func TestOutOfCharacterLineGetsNoResponse(t *testing.T) {
+	c := campaignFromYaml(t, "classifier:\n  prompts: drop\n", "Hier, bitte.", nil)
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "OOC: Foxie, reich mal die Chips rüber."})
+	select {
+	case resp := <-c.C():
+		t.Errorf("no actor should respond to table talk but %s said %q", resp.Actor.Name, resp.Text())
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	turns := make(turnRecorder, 1)
+	c.SetTurnPolicy(turns)
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie, was meinst du?"})
+	select {
+	case turn := <-turns:
+		if turn.Transcript != "Tharkhan: Foxie, was meinst du?" {
+			t.Errorf("expected out-of-character lines to be dropped from the turn but got:\n%s", turn.Transcript)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("turn policy was not asked who responds")
+	}
+}
+
+// gatedClassifier tags the line "first" only once it is released.
+type gatedClassifier struct {
+	release chan struct{}
+}
+
+func (g gatedClassifier) Classify(_ context.Context, line ClassifiedLine) (Tag, error) {
+	if line.Entry.Text == "first" {
+		<-g.release
+	}
+	return TagInCharacter, nil
+}
+
+func TestSlowClassifierKeepsOrder(t *testing.T) {
+	c, err := CampaignFromYaml([]byte("name: Test\nactors: []\n"))
+	if err != nil {
+		t.Fatalf("could not read campaign: %v", err)
+	}
+	classifier := gatedClassifier{release: make(chan struct{})}
+	c.SetClassifier(classifier)
+
+	done := make(chan struct{})
+	go func() {
+		c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "first"})
+		close(done)
+	}()
+	for c.Transcript.Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	c.HandleText(TranscriptEntry{SpeakerID: "5678", Name: "Lyra", Source: SourceSTT, Text: "second"})
+	close(classifier.release)
+	<-done
+
+	entries := c.Transcript.Entries()
+	if len(entries) != 2 || entries[0].Text != "first" || entries[1].Text != "second" {
+		t.Fatalf("expected the lines in the order they were spoken but got %+v", entries)
+	}
+	if entries[0].Tag != TagInCharacter || entries[1].Tag != TagInCharacter {
+		t.Errorf("expected both lines to be tagged but got %+v", entries)
+	}
+}
+
+func TestInvalidClassifier(t *testing.T) {
+	for name, classifier := range map[string]string{
+		"type":     "type: magic",
+		"handling": "prompts: hide",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := CampaignFromYaml([]byte("name: Test\nclassifier:\n  " + classifier + "\nactors: []\n")); err == nil {
+				t.Error("expected the classifier to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package pnp
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Actor of this campaign with the given name, ignoring case.
+func (c *Campaign) Actor(name string) (*Actor, bool) {
+	for _, actor := range c.CurrentActors() {
+		if strings.EqualFold(actor.Name, strings.TrimSpace(name)) {
+			return actor, true
+		}
+	}
+	return nil, false
+}
+
+// SetMuted decides whether the actor with the given name may be chosen to respond. Muted actors only speak when told to by Speak.
+// Returns the name of the actor as it is written in the campaign.
+func (c *Campaign) SetMuted(name string, muted bool) (string, error) {
+	actor, ok := c.Actor(name)
+	if !ok {
+		return "", fmt.Errorf("campaign %q has no actor %q", c.Name, name)
+	}
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if muted {
+		c.muted[actor.Name] = true
+	} else {
+		delete(c.muted, actor.Name)
+	}
+	slog.Info("changed whether actor is muted", "campaign", c.Name, "name", actor.Name, "muted", muted)
+	return actor.Name, nil
+}
+
+// IsMuted returns true if the actor with the given name is muted.
+func (c *Campaign) IsMuted(name string) bool {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return c.muted[name]
+}
+
+// Speak makes the actor with the given name respond to the current transcript right now, even if it is muted.
+// The actor follows the direction of the GM if it is not empty. Other actors may respond to it as usual.
+// Returns the name of the actor as it is written in the campaign.
+func (c *Campaign) Speak(name, direction string) (string, error) {
+	actor, ok := c.Actor(name)
+	if !ok {
+		return "", fmt.Errorf("campaign %q has no actor %q", c.Name, name)
+	}
+	c.transcriptMu.Lock()
+	entries := c.Transcript.Entries()
+	c.transcriptMu.Unlock()
+	concept := direction
+	if concept == "" && len(entries) > 0 {
+		concept = entries[len(entries)-1].Text
+	}
+	if !c.addResponder() {
+		return "", fmt.Errorf("campaign %q is closing", c.Name)
+	}
+	ex := c.startExchange(len(entries) - 1)
+	go func() {
+		defer c.responders.Done()
+		c.act(actor, concept, direction, ex, 0)
+	}()
+	return actor.Name, nil
+}
+
+// Line makes the actor with the given name say exactly the text, so the GM can decide what an NPC says.
+// The line is queued with the other responses and added to the transcript once it has been spoken.
+// Other actors may respond to it as usual. Returns the name of the actor as it is written in the campaign.
+func (c *Campaign) Line(name, text string) (string, error) {
+	actor, ok := c.Actor(name)
+	if !ok {
+		return "", fmt.Errorf("campaign %q has no actor %q", c.Name, name)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", errors.New("line must not be empty")
+	}
+	if _, ok := c.applyBudget(actor); !ok {
+		return "", errors.New("hard budget limit reached")
+	}
+	c.transcriptMu.Lock()
+	entries := c.Transcript.Entries()
+	c.transcriptMu.Unlock()
+	if !c.addResponder() {
+		return "", fmt.Errorf("campaign %q is closing", c.Name)
+	}
+	ex := c.startExchange(len(entries) - 1)
+	go func() {
+		defer c.responders.Done()
+		c.line(actor, text, ex)
+	}()
+	return actor.Name, nil
+}
+
+// line queues the text as response of the actor, split into sentences like a streamed response.
+func (c *Campaign) line(actor *Actor, text string, ex *exchange) {
+	start := time.Now()
+	var splitter sentenceSplitter
+	sentences := splitter.Write(text)
+	if rest := splitter.Flush(); rest != "" {
+		sentences = append(sentences, rest)
+	}
+	chunks := make(chan string, len(sentences))
+	for _, sentence := range sentences {
+		chunks <- sentence
+	}
+	close(chunks)
+
+	resp := newActorResponse(c.ctx, *actor, chunks)
+	c.trackResponse(resp.state)
+	defer c.untrackResponse(resp.state)
+	select {
+	case c.actorResponses <- resp:
+	case <-resp.Context().Done():
+		return
+	}
+	c.finishResponse(resp, TranscriptEntry{
+		SpeakerID:  actor.Name,
+		Name:       actor.Name,
+		Start:      start,
+		Source:     SourceNPC,
+		Text:       text,
+		ChainStart: ex.start,
+		ChainDepth: 1,
+	})
+}
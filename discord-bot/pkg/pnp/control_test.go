@@ -0,0 +1,123 @@
+package pnp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMutedActorIsNotChosen(t *testing.T) {
+	c := dialogueCampaign(t, 1, 10)
+	name, err := c.SetMuted("petra gabriel", true)
+	if err != nil {
+		t.Fatalf("could not mute actor: %v", err)
+	}
+	if name != "Petra Gabriel" {
+		t.Errorf("expected the name as written in the campaign but got %q", name)
+	}
+	if !c.IsMuted("Petra Gabriel") {
+		t.Fatal("expected Petra Gabriel to be muted")
+	}
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie, was meinst du?"})
+	if names := collectResponses(c, nil); len(names) != 0 {
+		t.Fatalf("expected nobody to respond but got %q", names)
+	}
+
+	if _, err := c.SetMuted("Petra Gabriel", false); err != nil {
+		t.Fatalf("could not unmute actor: %v", err)
+	}
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie?"})
+	if names := collectResponses(c, nil); fmt.Sprint(names) != "[Petra Gabriel]" {
+		t.Fatalf("expected Petra Gabriel to respond again but got %q", names)
+	}
+
+	if _, err := c.SetMuted("Nobody", true); err == nil {
+		t.Error("expected unknown actor to be rejected")
+	}
+}
+
+func TestSpeakWithDirection(t *testing.T) {
+	c := dialogueCampaign(t, 1, 10)
+	var received openai.ChatCompletionRequest
+	c.SetChatModel(newChatStandIn(t, "Ich weiß, wo der Schatz liegt.", &received))
+	if _, err := c.SetMuted("Brom", true); err != nil {
+		t.Fatalf("could not mute actor: %v", err)
+	}
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Hallo zusammen."})
+
+	if name, err := c.Speak("brom", "Erzähl vom Schatz."); err != nil || name != "Brom" {
+		t.Fatalf("could not make actor speak: %q, %v", name, err)
+	}
+	if names := collectResponses(c, nil); fmt.Sprint(names) != "[Brom]" {
+		t.Fatalf("expected the muted actor to speak when told to but got %q", names)
+	}
+	prompt := received.Messages[len(received.Messages)-1].Content
+	if !strings.Contains(prompt, "- GM DIRECTION -\nErzähl vom Schatz.") {
+		t.Errorf("expected the direction to be part of the prompt:\n%s", prompt)
+	}
+	if entries := c.Transcript.Entries(); len(entries) != 2 || entries[1].String() != "Brom: Ich weiß, wo der Schatz liegt." {
+		t.Errorf("expected the response in the transcript but got:\n%s", c.CurrentTranscript())
+	}
+
+	if _, err := c.Speak("Nobody", ""); err == nil {
+		t.Error("expected unknown actor to be rejected")
+	}
+}
+
+func TestLine(t *testing.T) {
+	c := dialogueCampaign(t, 1, 10)
+	var received openai.ChatCompletionRequest
+	c.SetChatModel(newChatStandIn(t, "Was?", &received))
+	if _, err := c.SetMuted("Brom", true); err != nil {
+		t.Fatalf("could not mute actor: %v", err)
+	}
+
+	if name, err := c.Line("brom", " Halt! Wer da? "); err != nil || name != "Brom" {
+		t.Fatalf("could not queue line: %q, %v", name, err)
+	}
+	var chunks []string
+	names := collectResponses(c, func(resp ActorResponse) {
+		for chunk := range resp.Chunks {
+			chunks = append(chunks, chunk)
+			resp.Spoken(chunk)
+		}
+	})
+	if fmt.Sprint(names) != "[Brom]" || fmt.Sprint(chunks) != "[Halt! Wer da?]" || len(chunks) != 2 {
+		t.Fatalf("expected the line of Brom in 2 sentences but got %q from %q", chunks, names)
+	}
+	if len(received.Messages) != 0 {
+		t.Error("expected the line to be spoken without asking the chat model")
+	}
+	if entries := c.Transcript.Entries(); len(entries) != 1 || entries[0].String() != "Brom: Halt! Wer da?" || entries[0].Source != SourceNPC {
+		t.Errorf("expected the line in the transcript but got:\n%s", c.CurrentTranscript())
+	}
+
+	if _, err := c.Line("Brom", " "); err == nil {
+		t.Error("expected empty line to be rejected")
+	}
+	if _, err := c.Line("Nobody", "Hallo"); err == nil {
+		t.Error("expected unknown actor to be rejected")
+	}
+}
+
+func TestMutedActorsAreJournaled(t *testing.T) {
+	c := dialogueCampaign(t, 1, 10)
+	if _, err := c.SetMuted("Brom", true); err != nil {
+		t.Fatalf("could not mute actor: %v", err)
+	}
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		t.Fatalf("could not marshal campaign: %v", err)
+	}
+	resumed, err := CampaignFromYaml(data)
+	if err != nil {
+		t.Fatalf("could not restore campaign: %v", err)
+	}
+	if !resumed.IsMuted("Brom") || resumed.IsMuted("Petra Gabriel") {
+		t.Errorf("muted actors were not restored from:\n%s", data)
+	}
+}
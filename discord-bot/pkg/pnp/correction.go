@@ -0,0 +1,185 @@
+package pnp
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+)
+
+//go:embed correction_prompt.tpl
+var correctionPromptText string
+
+var correctionPromptTemplate *template.Template
+
+func init() {
+	var err error
+	// Check if the correction prompt template can be resolved.
+	correctionPromptTemplate, err = template.New("correction").Parse(correctionPromptText)
+	if err != nil {
+		panic(fmt.Errorf("could not parse correction prompt template: %w", err))
+	}
+	if err := checkSummarizationTemplate(correctionPromptTemplate); err != nil {
+		panic(fmt.Errorf("correction prompt template can not be executed: %w", err))
+	}
+}
+
+const defaultCorrectionChunkLines = 100
+
+// CorrectionConfig configures the correction of the transcript once the session has ended.
+type CorrectionConfig struct {
+	// Enabled lets a LLM fix misheard names and merge fragmented lines before the transcript is stored.
+	Enabled bool `yaml:"enabled"`
+	// Model to use instead of the default model of the campaign.
+	Model string `yaml:"model"`
+	// ChunkLines of the transcript that are corrected with one request. Defaults to 100.
+	ChunkLines int `yaml:"chunkLines"`
+}
+
+// Validate that the chunks are usable.
+func (cfg CorrectionConfig) Validate() error {
+	if cfg.ChunkLines < 0 {
+		return fmt.Errorf("correction chunk lines must not be negative but are %d", cfg.ChunkLines)
+	}
+	return nil
+}
+
+func (cfg CorrectionConfig) chunkLines() int {
+	if cfg.ChunkLines == 0 {
+		return defaultCorrectionChunkLines
+	}
+	return cfg.ChunkLines
+}
+
+// Correction of the transcript of a session that the game master can review before it is stored.
+type Correction struct {
+	// Original transcript as Close would store it without the correction.
+	Original string
+	// Corrected transcript.
+	Corrected string
+	chunks    []correctedChunk
+}
+
+type correctedChunk struct {
+	original, corrected []string
+}
+
+// Diff of the original and the corrected transcript in the unified format. Empty if nothing has been corrected.
+func (cor Correction) Diff() string {
+	var sb strings.Builder
+	var offsetOriginal, offsetCorrected int
+	for _, chunk := range cor.chunks {
+		writeUnifiedDiff(&sb, chunk.original, chunk.corrected, offsetOriginal, offsetCorrected)
+		offsetOriginal += len(chunk.original)
+		offsetCorrected += len(chunk.corrected)
+	}
+	return sb.String()
+}
+
+// Changed returns true if the correction differs from the original transcript.
+func (cor Correction) Changed() bool {
+	return cor.Original != cor.Corrected
+}
+
+// CorrectTranscript lets a LLM fix misheard names and merge fragmented lines of the transcript as Close would store it.
+// The players, actors and glossary of the campaign tell it how words are spelled. The transcript is corrected in chunks
+// and chunks that can not be corrected are kept as they are.
+//
+// The transcript of the campaign stays unchanged. Call AcceptCorrection once the game master reviewed the Diff to
+// store the corrected transcript instead.
+func (c *Campaign) CorrectTranscript() (Correction, error) {
+	c.castMu.RLock()
+	promptBuf := bytes.NewBuffer(make([]byte, 0))
+	err := c.prompts.correction.Execute(promptBuf, c.promptData())
+	c.castMu.RUnlock()
+	if err != nil {
+		return Correction{}, fmt.Errorf("could not resolve correction prompt template: %w", err)
+	}
+	original := c.Classifier.render(c.Transcript.Entries(), c.Classifier.Storage)
+	correction := Correction{Original: original}
+	if original == "" {
+		return correction, nil
+	}
+
+	lines := strings.Split(original, "\n")
+	corrected := make([]string, 0, len(lines))
+	for start := 0; start < len(lines); start += c.Correction.chunkLines() {
+		chunk := lines[start:min(start+c.Correction.chunkLines(), len(lines))]
+		fixed, err := c.correctChunk(promptBuf.String(), chunk)
+		if err != nil {
+			slog.Warn("could not correct part of the transcript, keeping it as it is", "campaign", c.Name, "line", start+1, "error", err)
+			fixed = chunk
+		}
+		correction.chunks = append(correction.chunks, correctedChunk{original: chunk, corrected: fixed})
+		corrected = append(corrected, fixed...)
+	}
+	correction.Corrected = strings.Join(corrected, "\n")
+	return correction, nil
+}
+
+// correctChunk of transcript lines with the system prompt.
+func (c *Campaign) correctChunk(systemPrompt string, chunk []string) ([]string, error) {
+	resp, err := c.model(UsageCorrection).Chat(context.Background(), llm.Request{
+		Model: c.Correction.Model,
+		Messages: []llm.Message{
+			{
+				Role:    llm.RoleSystem,
+				Content: systemPrompt,
+			},
+			{
+				Role:    llm.RoleUser,
+				Content: strings.Join(chunk, "\n"),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	corrected := make([]string, 0, len(chunk))
+	for _, line := range strings.Split(resp.Content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if name, _, ok := strings.Cut(line, ": "); !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("corrected line %q is not in the format \"Name: text\"", line)
+		}
+		corrected = append(corrected, line)
+	}
+	if len(corrected) == 0 {
+		return nil, fmt.Errorf("correction of %d lines is empty", len(chunk))
+	}
+	return corrected, nil
+}
+
+// AcceptCorrection so Close stores the corrected transcript instead of the original one.
+// The original transcript is archived in the OriginalCollection.
+func (c *Campaign) AcceptCorrection(correction Correction) {
+	c.transcriptMu.Lock()
+	defer c.transcriptMu.Unlock()
+	c.corrected = correction.Corrected
+}
+
+// OriginalCollection is the name of the vector DB collection that archives the original transcripts of the sessions
+// whose corrected transcript was stored instead. Each transcript is stored as document named after the start of its session.
+func (c *Campaign) OriginalCollection() string {
+	return c.Name + "Original"
+}
+
+// storedTranscript is the text that Close stores in the vector DB. original is the transcript as it was recognized
+// if a correction was accepted and empty otherwise.
+func (c *Campaign) storedTranscript() (transcript, original string) {
+	c.transcriptMu.Lock()
+	corrected := c.corrected
+	c.transcriptMu.Unlock()
+	rendered := c.Classifier.render(c.Transcript.Entries(), c.Classifier.Storage)
+	if corrected != "" {
+		return corrected, rendered
+	}
+	return rendered, ""
+}
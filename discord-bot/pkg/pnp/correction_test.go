@@ -0,0 +1,107 @@
+package pnp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const correctionCampaignYaml = `players:
+  "1234": Amon
+glossary:
+  - Interlumen
+  - Sonnenturm
+correction:
+  enabled: true
+  model: gpt-4o-mini
+  chunkLines: 3
+`
+
+func correctionCampaign(t *testing.T, answer string, received *openai.ChatCompletionRequest) *Campaign {
+	t.Helper()
+	c := campaignFromYaml(t, correctionCampaignYaml, answer, received)
+	for _, entry := range []TranscriptEntry{
+		{Name: "Amon", Text: "Wir waren,"},
+		{Name: "GameMaster", Text: "Ne,"},
+		{Name: "Amon", Text: "glaube ich, auf dem Weg zum Sonnen Turm."},
+		{Name: "GameMaster", Text: "Genau."},
+	} {
+		c.Transcript.Append(entry)
+	}
+	return c
+}
+
+func TestCorrectTranscript(t *testing.T) {
+	var received openai.ChatCompletionRequest
+	c := correctionCampaign(t, "Amon: Wir waren, glaube ich, auf dem Weg zum Sonnenturm.\nGameMaster: Ne,", &received)
+
+	correction, err := c.CorrectTranscript()
+	if err != nil {
+		t.Fatalf("could not correct transcript: %v", err)
+	}
+	system := received.Messages[0].Content
+	if received.Model != "gpt-4o-mini" || !strings.Contains(system, "players are: Amon") || !strings.Contains(system, "Words of the campaign: Interlumen, Sonnenturm") {
+		t.Errorf("unexpected correction request with system prompt:\n%s", system)
+	}
+	// The stand-in answers the same for the second chunk too
+	expected := "Amon: Wir waren, glaube ich, auf dem Weg zum Sonnenturm.\nGameMaster: Ne,\nAmon: Wir waren, glaube ich, auf dem Weg zum Sonnenturm.\nGameMaster: Ne,"
+	if correction.Corrected != expected {
+		t.Errorf("expected corrected transcript:\n%s\n\nbut got:\n%s", expected, correction.Corrected)
+	}
+	if correction.Original != c.CurrentTranscript() {
+		t.Errorf("expected the original transcript to be kept but got:\n%s", correction.Original)
+	}
+	if !strings.HasPrefix(correction.Diff(), "@@ -1,3 +1,2 @@\n-Amon: Wir waren,\n+Amon: Wir waren, glaube ich, auf dem Weg zum Sonnenturm.\n") {
+		t.Errorf("unexpected diff:\n%s", correction.Diff())
+	}
+
+	if stored, original := c.storedTranscript(); stored != correction.Original || original != "" {
+		t.Errorf("expected only the original transcript to be stored before the correction is accepted but got:\n%s", stored)
+	}
+	c.AcceptCorrection(correction)
+	stored, original := c.storedTranscript()
+	if stored != expected {
+		t.Errorf("expected the corrected transcript to be stored but got:\n%s", stored)
+	}
+	if original != correction.Original {
+		t.Errorf("expected the original transcript to be archived but got:\n%s", original)
+	}
+}
+
+func TestSummaryUsesCorrectedTranscript(t *testing.T) {
+	var received openai.ChatCompletionRequest
+	c := correctionCampaign(t, "{}", &received)
+	corrected := "Amon: Wir waren, glaube ich, auf dem Weg zum Sonnenturm.\nGameMaster: Genau."
+	c.AcceptCorrection(Correction{Original: c.CurrentTranscript(), Corrected: corrected})
+
+	if _, err := c.Summary(); err != nil {
+		t.Fatalf("could not summarize session: %v", err)
+	}
+	if transcript := received.Messages[1].Content; transcript != corrected {
+		t.Errorf("expected the corrected transcript to be summarized but got:\n%s", transcript)
+	}
+}
+
+func TestCorrectTranscriptKeepsUnexpectedFormat(t *testing.T) {
+	c := correctionCampaign(t, "Hier ist das korrigierte Transkript:\nAmon: Wir waren...", nil)
+
+	correction, err := c.CorrectTranscript()
+	if err != nil {
+		t.Fatalf("could not correct transcript: %v", err)
+	}
+	if correction.Changed() || correction.Diff() != "" {
+		t.Errorf("expected chunks in an unexpected format to be kept but got:\n%s", correction.Diff())
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	original := []string{"a", "b", "c", "d", "e", "f", "g"}
+	corrected := []string{"a", "B", "c", "d", "e", "f", "g", "h"}
+	var sb strings.Builder
+	writeUnifiedDiff(&sb, original, corrected, 10, 20)
+	expected := "@@ -11,3 +21,3 @@\n a\n-b\n+B\n c\n@@ -17,1 +27,2 @@\n g\n+h\n"
+	if sb.String() != expected {
+		t.Errorf("expected diff:\n%s\nbut got:\n%s", expected, sb.String())
+	}
+}
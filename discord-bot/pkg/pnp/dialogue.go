@@ -0,0 +1,104 @@
+package pnp
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultMaxChain  = 3
+	defaultPerMinute = 6
+)
+
+// DialogueConfig bounds the exchanges between actors that a single player line can start.
+type DialogueConfig struct {
+	// MaxChain of actor responses that may follow a single player line. Defaults to 3. Set to 1 to disable NPC-to-NPC dialogue.
+	MaxChain int `yaml:"maxChain"`
+	// PerMinute limits how many actors may respond to other actors within a minute. Defaults to 6.
+	PerMinute int `yaml:"perMinute"`
+}
+
+// Validate that the limits are not negative.
+func (cfg DialogueConfig) Validate() error {
+	var err error
+	if cfg.MaxChain < 0 {
+		err = errors.Join(err, fmt.Errorf("maxChain must not be negative but is %d", cfg.MaxChain))
+	}
+	if cfg.PerMinute < 0 {
+		err = errors.Join(err, fmt.Errorf("perMinute must not be negative but is %d", cfg.PerMinute))
+	}
+	return err
+}
+
+func (cfg DialogueConfig) maxChain() int {
+	if cfg.MaxChain == 0 {
+		return defaultMaxChain
+	}
+	return cfg.MaxChain
+}
+
+func (cfg DialogueConfig) perMinute() int {
+	if cfg.PerMinute == 0 {
+		return defaultPerMinute
+	}
+	return cfg.PerMinute
+}
+
+// exchange of actor responses that was started by a single player line.
+type exchange struct {
+	// start is the line number of the player line that started the exchange.
+	start  int
+	broken atomic.Bool
+}
+
+// startExchange for the player line with the given index in the transcript.
+func (c *Campaign) startExchange(index int) *exchange {
+	ex := &exchange{start: index + 1}
+	c.responsesMu.Lock()
+	defer c.responsesMu.Unlock()
+	c.exchange = ex
+	return ex
+}
+
+// currentExchange that the actor line belongs to. Returns nil if the exchange has been broken or replaced meanwhile.
+func (c *Campaign) currentExchange(entry TranscriptEntry) *exchange {
+	c.responsesMu.Lock()
+	defer c.responsesMu.Unlock()
+	if c.exchange == nil || c.exchange.start != entry.ChainStart || c.exchange.broken.Load() {
+		return nil
+	}
+	return c.exchange
+}
+
+// reserveChainResponse from the per-minute budget for an actor that responds to another actor. Returns false if the budget is used up.
+func (c *Campaign) reserveChainResponse(chainStart int) bool {
+	c.responsesMu.Lock()
+	defer c.responsesMu.Unlock()
+	now := time.Now()
+	recent := c.chainResponses[:0]
+	for _, t := range c.chainResponses {
+		if now.Sub(t) < time.Minute {
+			recent = append(recent, t)
+		}
+	}
+	c.chainResponses = recent
+	if len(c.chainResponses) >= c.Dialogue.perMinute() {
+		slog.Info("exchange between actors exceeded the budget per minute", "campaign", c.Name, "chainStart", chainStart, "perMinute", c.Dialogue.perMinute())
+		return false
+	}
+	c.chainResponses = append(c.chainResponses, now)
+	return true
+}
+
+// BreakChain stops the running exchange between actors and interrupts all of their responses.
+// Actors will only respond again once a player says something. Returns true if anything was stopped.
+func (c *Campaign) BreakChain() bool {
+	c.responsesMu.Lock()
+	broken := c.exchange != nil && !c.exchange.broken.Swap(true)
+	c.responsesMu.Unlock()
+	interrupted := c.Interrupt()
+	return broken || interrupted
+}
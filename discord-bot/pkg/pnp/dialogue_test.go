@@ -0,0 +1,110 @@
+package pnp
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+const dialogueCampaignYaml = `dialogue:
+  maxChain: %d
+  perMinute: %d
+actors:
+  - name: Petra Gabriel
+    aliases:
+      - Foxie
+    voice: nova
+    script: Du bist Petra.
+  - name: Brom
+    voice: onyx
+    script: Du bist Brom.
+`
+
+// dialogueCampaign in which every actor response addresses the other actor.
+func dialogueCampaign(t *testing.T, maxChain, perMinute int) *Campaign {
+	t.Helper()
+	return campaignFromYaml(t, fmt.Sprintf(dialogueCampaignYaml, maxChain, perMinute), "Brom und Foxie, hört mal zu!", nil)
+}
+
+// collectResponses until no actor responded for a while.
+func collectResponses(c *Campaign, onResponse func(ActorResponse)) []string {
+	names := make([]string, 0)
+	for {
+		select {
+		case resp := <-c.C():
+			names = append(names, resp.Actor.Name)
+			if onResponse != nil {
+				onResponse(resp)
+			}
+			resp.Text()
+		case <-time.After(300 * time.Millisecond):
+			return names
+		}
+	}
+}
+
+func TestDialogueChainIsBounded(t *testing.T) {
+	c := dialogueCampaign(t, 3, 10)
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie, was meinst du?"})
+
+	names := collectResponses(c, nil)
+	if fmt.Sprint(names) != "[Petra Gabriel Brom Petra Gabriel]" {
+		t.Fatalf("expected 3 alternating responses but got %q", names)
+	}
+	entries := c.Transcript.Entries()
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 lines but transcript is:\n%s", c.CurrentTranscript())
+	}
+	for depth, entry := range entries[1:] {
+		if entry.ChainStart != 1 || entry.ChainDepth != depth+1 {
+			t.Errorf("expected line %d to be marked as part of the exchange started by line 1 but got %+v", depth+2, entry)
+		}
+	}
+}
+
+func TestDialogueBudgetPerMinute(t *testing.T) {
+	c := dialogueCampaign(t, 10, 1)
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie, was meinst du?"})
+
+	// The direct response is free, only one actor may respond to another actor
+	if names := collectResponses(c, nil); len(names) != 2 {
+		t.Fatalf("expected 2 responses but got %q", names)
+	}
+
+	// Players are always answered even if the budget is used up
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Brom, und du?"})
+	if names := collectResponses(c, nil); fmt.Sprint(names) != "[Brom]" {
+		t.Fatalf("expected only Brom to respond but got %q", names)
+	}
+}
+
+func TestBreakChain(t *testing.T) {
+	c := dialogueCampaign(t, 10, 10)
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie, was meinst du?"})
+
+	names := collectResponses(c, func(resp ActorResponse) {
+		if resp.Actor.Name == "Brom" && !c.BreakChain() {
+			t.Error("expected the exchange to be broken")
+		}
+	})
+	if fmt.Sprint(names) != "[Petra Gabriel Brom]" {
+		t.Fatalf("expected the exchange to stop after Brom but got %q", names)
+	}
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie?"})
+	if names := collectResponses(c, nil); len(names) == 0 {
+		t.Error("expected a new player line to start a new exchange")
+	}
+}
+
+func TestDialogueConfigValidate(t *testing.T) {
+	if err := (DialogueConfig{}).Validate(); err != nil {
+		t.Errorf("expected defaults to be valid but got %v", err)
+	}
+	if err := (DialogueConfig{MaxChain: -1, PerMinute: -1}).Validate(); err == nil {
+		t.Error("expected negative limits to be invalid")
+	}
+}
@@ -0,0 +1,103 @@
+package pnp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the amount of unchanged lines that are shown around every change.
+const diffContext = 1
+
+// diffOp is a line that is kept (' '), removed ('-') or added ('+').
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines computes the shortest edit script between the lines of a and b by their longest common subsequence.
+// Needs len(a)*len(b) memory, so it should only be used for small parts of a transcript.
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	ops := make([]diffOp, 0, max(len(a), len(b)))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+	return ops
+}
+
+// writeUnifiedDiff of the lines a and b in the unified format. The line numbers in the hunk headers start after
+// offsetA and offsetB, so the diffs of consecutive parts can be written one after the other.
+func writeUnifiedDiff(sb *strings.Builder, a, b []string, offsetA, offsetB int) {
+	ops := diffLines(a, b)
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+		// Extend the hunk until the changes are more than twice the context apart
+		end := start
+		for next := start; next < len(ops); next++ {
+			if ops[next].kind != ' ' {
+				end = next + 1
+			} else if next-end >= 2*diffContext {
+				break
+			}
+		}
+		from, to := max(start-diffContext, 0), min(end+diffContext, len(ops))
+
+		lineA, lineB := offsetA+1, offsetB+1
+		for _, op := range ops[:from] {
+			if op.kind != '+' {
+				lineA++
+			}
+			if op.kind != '-' {
+				lineB++
+			}
+		}
+		var countA, countB int
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				countA++
+			}
+			if op.kind != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", lineA, countA, lineB, countB)
+		for _, op := range ops[from:to] {
+			fmt.Fprintf(sb, "%c%s\n", op.kind, op.line)
+		}
+		start = to
+	}
+}
@@ -0,0 +1,134 @@
+package pnp
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// sttPromptTokenLimit of Whisper. Longer prompts are cut off at their start.
+const sttPromptTokenLimit = 224
+
+// sttPromptTokens estimates the tokens of the text for Whisper without undercounting. Whisper uses the byte level
+// GPT-2 vocabulary which splits made up names into pieces of a few letters and numbers into single digits, so every
+// two letters, every digit, every other character and every byte of a non-ASCII rune count as one token.
+// Spaces are merged into the following word.
+func sttPromptTokens(text string) int {
+	tokens, letters := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf && unicode.IsLetter(r) {
+			letters++
+			continue
+		}
+		tokens += (letters + 1) / 2
+		letters = 0
+		switch {
+		case r >= utf8.RuneSelf:
+			tokens += utf8.RuneLen(r)
+		case !unicode.IsSpace(r):
+			tokens++
+		}
+	}
+	return tokens + (letters+1)/2
+}
+
+// glossaryChunks of the stored transcript of the last session that are searched for recently used glossary terms.
+const glossaryChunks = 10
+
+// GlossaryTerm of the campaign like a place, item or faction that the speech recognition should know. It is given either
+// as the term alone or with hints like:
+//
+//	glossary:
+//	  - Interlumen
+//	  - term: Xyl'thar
+//	    hints: [Zil-tar, Sil Tar]
+type GlossaryTerm struct {
+	// Term as it is spelled.
+	Term string `yaml:"term"`
+	// Hints how the term sounds or is often misheard. Can be empty.
+	Hints []string `yaml:"hints,omitempty"`
+}
+
+// UnmarshalYAML reads the term alone or with its hints.
+func (t *GlossaryTerm) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		t.Term = strings.TrimSpace(value.Value)
+	} else {
+		type plain GlossaryTerm
+		var tmp plain
+		if err := value.Decode(&tmp); err != nil {
+			return err
+		}
+		*t = GlossaryTerm(tmp)
+		t.Term = strings.TrimSpace(t.Term)
+	}
+	if t.Term == "" {
+		return errors.New("glossary term must not be empty")
+	}
+	return nil
+}
+
+// MarshalYAML stores terms without hints as the term alone.
+func (t GlossaryTerm) MarshalYAML() (any, error) {
+	if len(t.Hints) == 0 {
+		return t.Term, nil
+	}
+	type plain GlossaryTerm
+	return plain(t), nil
+}
+
+// String returns the term, so templates can print it directly.
+func (t GlossaryTerm) String() string {
+	return t.Term
+}
+
+// lastMention of the term or one of its hints in the lowercase text. Returns -1 if it is not mentioned.
+func (t GlossaryTerm) lastMention(text string) int {
+	last := strings.LastIndex(text, strings.ToLower(t.Term))
+	for _, hint := range t.Hints {
+		last = max(last, strings.LastIndex(text, strings.ToLower(hint)))
+	}
+	return last
+}
+
+// rankGlossary by the last mention of the terms in the recent text, the most recent first. Terms that were not
+// mentioned follow in their original order.
+func rankGlossary(glossary []GlossaryTerm, recent string) []GlossaryTerm {
+	recent = strings.ToLower(recent)
+	mentions := make(map[string]int, len(glossary))
+	for _, term := range glossary {
+		mentions[term.Term] = term.lastMention(recent)
+	}
+	ranked := slices.Clone(glossary)
+	slices.SortStableFunc(ranked, func(a, b GlossaryTerm) int {
+		return mentions[b.Term] - mentions[a.Term]
+	})
+	return ranked
+}
+
+// recentText of the campaign in which the glossary terms are looked up: the end of the stored transcript of the last
+// session followed by the running session.
+func (c *Campaign) recentText() string {
+	var sb strings.Builder
+	if c.dbClient != nil {
+		chunks, err := c.dbClient.LatestChunks(c.Name, glossaryChunks)
+		if err != nil {
+			slog.Warn("could not read transcripts of earlier sessions for the glossary", "campaign", c.Name, "error", err)
+		}
+		for _, chunk := range chunks {
+			sb.WriteString(chunk)
+			sb.WriteString("\n")
+		}
+	}
+	c.transcriptMu.Lock()
+	sb.WriteString(c.StorySoFar)
+	c.transcriptMu.Unlock()
+	sb.WriteString("\n")
+	sb.WriteString(c.Transcript.String())
+	return sb.String()
+}
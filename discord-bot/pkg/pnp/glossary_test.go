@@ -0,0 +1,90 @@
+package pnp
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const glossaryCampaignYaml = `glossary:
+  - Interlumen
+  - term: Xyl'thar
+    hints: [Zil-tar, Sil Tar]
+`
+
+func TestGlossaryYaml(t *testing.T) {
+	c := campaignFromYaml(t, glossaryCampaignYaml, "", nil)
+	if len(c.Glossary) != 2 || c.Glossary[0].Term != "Interlumen" || c.Glossary[1].Term != "Xyl'thar" || len(c.Glossary[1].Hints) != 2 {
+		t.Fatalf("unexpected glossary %+v", c.Glossary)
+	}
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		t.Fatalf("could not marshal campaign: %v", err)
+	}
+	if !strings.Contains(string(data), "- Interlumen\n") {
+		t.Errorf("expected terms without hints to be stored as the term alone:\n%s", data)
+	}
+	resumed, err := CampaignFromYaml(data)
+	if err != nil {
+		t.Fatalf("could not restore campaign: %v", err)
+	}
+	if fmt.Sprint(resumed.Glossary) != fmt.Sprint(c.Glossary) || resumed.Glossary[1].Hints[1] != "Sil Tar" {
+		t.Errorf("expected glossary %+v but got %+v", c.Glossary, resumed.Glossary)
+	}
+
+	if _, err := CampaignFromYaml([]byte("name: Test\nglossary:\n  - term: \" \"\nactors: []\n")); err == nil {
+		t.Error("expected an empty glossary term to be rejected")
+	}
+}
+
+func TestSTTPromptRanksGlossary(t *testing.T) {
+	c := campaignFromYaml(t, glossaryCampaignYaml, "", nil)
+	for i := range 200 {
+		c.Glossary = append(c.Glossary, GlossaryTerm{Term: fmt.Sprintf("Ort%03d", i)})
+	}
+	c.Transcript.Append(TranscriptEntry{Name: "Amon", Text: "Wir gehen nach Ort150."})
+	c.Transcript.Append(TranscriptEntry{Name: "Brom", Text: "Nicht zu Sil Tar!"})
+
+	prompt, err := c.STTPrompt()
+	if err != nil {
+		t.Fatalf("could not create STT prompt: %v", err)
+	}
+	if tokens := sttPromptTokens(prompt); tokens > sttPromptTokenLimit {
+		t.Errorf("expected the prompt to fit into %d tokens but it has %d:\n%s", sttPromptTokenLimit, tokens, prompt)
+	}
+	if !strings.HasSuffix(prompt, "Ort001,Ort000,Interlumen,Ort150,Xyl'thar,.") {
+		t.Errorf("expected the recently mentioned terms last:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "Ort199") {
+		t.Errorf("expected the least recent terms to be left out:\n%s", prompt)
+	}
+}
+
+func TestSTTPromptTokens(t *testing.T) {
+	tests := map[string]int{
+		"":                0,
+		"Amon":            2,
+		"Ort150,":         6,
+		"Xyl'thar Hüsten": 10,
+		"a  b":            2,
+	}
+	for text, expected := range tests {
+		if actual := sttPromptTokens(text); actual != expected {
+			t.Errorf("expected %q to count %d tokens but got %d", text, expected, actual)
+		}
+	}
+}
+
+func TestCorrectionPromptHasGlossaryHints(t *testing.T) {
+	c := campaignFromYaml(t, glossaryCampaignYaml, "", nil)
+	var prompt bytes.Buffer
+	if err := c.prompts.correction.Execute(&prompt, c.promptData()); err != nil {
+		t.Fatalf("could not execute correction prompt: %v", err)
+	}
+	if !strings.Contains(prompt.String(), "Words of the campaign: Interlumen, Xyl'thar (sounds like Zil-tar or Sil Tar)") {
+		t.Errorf("expected the glossary with hints in the correction prompt:\n%s", prompt.String())
+	}
+}
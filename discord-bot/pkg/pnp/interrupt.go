@@ -0,0 +1,126 @@
+package pnp
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Names of the available interruption policies as used in the campaign YAML.
+const (
+	// InterruptOnSpeech stops all actors that are being played as soon as a player speaks for a moment or says a stop word.
+	InterruptOnSpeech = "speech"
+	// InterruptOnStopWord stops all actors only if a player says a stop word.
+	InterruptOnStopWord = "stop-word"
+	// InterruptNever lets actors always finish their responses.
+	InterruptNever = "never"
+)
+
+// wordsPerSecond of a typical TTS voice. Used to estimate how much of a sentence was spoken before it got interrupted.
+const wordsPerSecond = 2.5
+
+var defaultStopWords = []string{"stop"}
+
+// defaultMinSpeech a player has to speak before actors are interrupted, so coughs or a short "mhm" don't stop them.
+const defaultMinSpeech = 600 * time.Millisecond
+
+// InterruptionConfig decides when actors stop speaking.
+type InterruptionConfig struct {
+	// Policy of the interruption. One of speech (default), stop-word or never.
+	Policy string `yaml:"policy"`
+	// StopWords that immediately stop all actors when a player says them. Defaults to "stop".
+	StopWords []string `yaml:"stopWords"`
+	// MinSpeech a player has to speak without a pause before the speech policy interrupts actors. Defaults to 600ms.
+	MinSpeech time.Duration `yaml:"minSpeech"`
+}
+
+// Validate that the config describes a valid policy.
+func (cfg InterruptionConfig) Validate() error {
+	var err error
+	switch cfg.Policy {
+	case "", InterruptOnSpeech, InterruptOnStopWord, InterruptNever:
+	default:
+		err = errors.Join(err, fmt.Errorf("unknown interruption policy %q", cfg.Policy))
+	}
+	if slices.ContainsFunc(cfg.StopWords, func(word string) bool { return removeNonWordRunes(strings.TrimSpace(word)) == "" }) {
+		err = errors.Join(err, errors.New("stop words must not be empty"))
+	}
+	if cfg.MinSpeech < 0 {
+		err = errors.Join(err, errors.New("minimum speech duration must not be negative"))
+	}
+	return err
+}
+
+// onSpeech returns true if the policy interrupts actors once a player has been speaking for the given duration.
+func (cfg InterruptionConfig) onSpeech(speaking time.Duration) bool {
+	if cfg.Policy != "" && cfg.Policy != InterruptOnSpeech {
+		return false
+	}
+	minSpeech := cfg.MinSpeech
+	if minSpeech == 0 {
+		minSpeech = defaultMinSpeech
+	}
+	return speaking >= minSpeech
+}
+
+// containsStopWord returns true if the policy allows stop words and any of them is part of the text.
+func (cfg InterruptionConfig) containsStopWord(text string) bool {
+	if cfg.Policy == InterruptNever {
+		return false
+	}
+	stopWords := cfg.StopWords
+	if len(stopWords) == 0 {
+		stopWords = defaultStopWords
+	}
+	words := strings.Fields(strings.ToLower(text))
+	for i, word := range words {
+		words[i] = removeNonWordRunes(word)
+	}
+	return slices.ContainsFunc(stopWords, func(stopWord string) bool {
+		return slices.Contains(words, strings.ToLower(removeNonWordRunes(strings.TrimSpace(stopWord))))
+	})
+}
+
+// spokenWords estimates the start of sentence that fits into the played duration.
+func spokenWords(sentence string, played time.Duration) string {
+	words := strings.Fields(sentence)
+	n := min(int(played.Seconds()*wordsPerSecond), len(words))
+	return strings.Join(words[:n], " ")
+}
+
+// PlayerSpeaking notifies the campaign that the player with the given speaker ID has been speaking for the given duration without a pause.
+// Depending on the interruption policy all actors whose responses are being played will stop. Responses that are still
+// generated or converted to speech are kept, so table noise can't drop them before they are heard.
+func (c *Campaign) PlayerSpeaking(speakerID string, speaking time.Duration) {
+	if !c.Interruption.onSpeech(speaking) {
+		return
+	}
+	if c.interrupt(true) {
+		slog.Info("player interrupted actors", "campaign", c.Name, "speakerID", speakerID, "speaking", speaking)
+	}
+}
+
+// Interrupt all actor responses that are currently generated or spoken. Returns true if any response was interrupted.
+func (c *Campaign) Interrupt() bool {
+	return c.interrupt(false)
+}
+
+// interrupt the active responses or only the ones whose playback has started. Returns true if any response was interrupted.
+func (c *Campaign) interrupt(onlyPlaying bool) bool {
+	c.responsesMu.Lock()
+	defer c.responsesMu.Unlock()
+	interrupted := false
+	for resp := range c.activeResponses {
+		if onlyPlaying && !resp.playing.Load() {
+			continue
+		}
+		if resp.ctx.Err() == nil {
+			resp.cancel()
+			interrupted = true
+		}
+	}
+	return interrupted
+}
@@ -0,0 +1,92 @@
+package pnp
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ResumeCampaign from the journal at path so the session continues where it stopped.
+func ResumeCampaign(path string) (*Campaign, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read journal: %w", err)
+	}
+	c, err := CampaignFromYaml(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not restore campaign from journal %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// StartJournal writes the session state to path every interval until the campaign is closed.
+// The journal will be removed once Close() stored the transcript successfully.
+func (c *Campaign) StartJournal(path string, interval time.Duration) {
+	c.journalMu.Lock()
+	c.journalPath = path
+	c.journalMu.Unlock()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.FlushJournal(); err != nil {
+					slog.Warn("could not write journal", "campaign", c.Name, "path", path, "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// FlushJournal writes the session state to the journal immediately and saves the usage ledger.
+// The journal is not written if StartJournal hasn't been called.
+func (c *Campaign) FlushJournal() error {
+	c.journalMu.Lock()
+	defer c.journalMu.Unlock()
+	if err := c.ledger.Save(); err != nil {
+		slog.Warn("could not save usage ledger", "campaign", c.Name, "error", err)
+	}
+	if c.journalPath == "" {
+		return nil
+	}
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("could not marshal campaign: %w", err)
+	}
+	// Write to a temporary file first so a crash midway never corrupts the previous journal.
+	tmp, err := os.CreateTemp(filepath.Dir(c.journalPath), filepath.Base(c.journalPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.journalPath)
+}
+
+// removeJournal once the session has been stored for good.
+func (c *Campaign) removeJournal() error {
+	c.journalMu.Lock()
+	defer c.journalMu.Unlock()
+	if c.journalPath == "" {
+		return nil
+	}
+	if err := os.Remove(c.journalPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
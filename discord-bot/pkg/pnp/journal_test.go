@@ -0,0 +1,64 @@
+package pnp
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournalRoundTrip(t *testing.T) {
+	c := testCampaign(t, "")
+	c.Actors[0].Temperature = temperature(0.7)
+	c.StorySoFar = "Die Helden sind aufgebrochen."
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Wo sind wir eigentlich gerade?"})
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Im Wald."})
+
+	path := filepath.Join(t.TempDir(), "Test-journal.yml")
+	c.StartJournal(path, time.Hour)
+	if err := c.FlushJournal(); err != nil {
+		t.Fatalf("could not write journal: %v", err)
+	}
+
+	resumed, err := ResumeCampaign(path)
+	if err != nil {
+		t.Fatalf("could not resume campaign: %v", err)
+	}
+	if resumed.CurrentTranscript() != c.CurrentTranscript() {
+		t.Errorf("expected transcript:\n%s\n\nbut got:\n%s", c.CurrentTranscript(), resumed.CurrentTranscript())
+	}
+	if entry := resumed.Transcript.Entries()[0]; entry.SpeakerID != "1234" || entry.Source != SourceSTT {
+		t.Errorf("transcript entry lost its metadata: %+v", entry)
+	}
+	if !resumed.StartedAt.Equal(c.StartedAt) {
+		t.Errorf("expected session to have started at %v but got %v", c.StartedAt, resumed.StartedAt)
+	}
+	if resumed.StorySoFar != c.StorySoFar || resumed.Players["1234"] != "Tharkhan" {
+		t.Errorf("campaign state was not restored: %+v", resumed)
+	}
+	if len(resumed.Actors) != 1 || resumed.Actors[0].Temperature == nil || *resumed.Actors[0].Temperature != 0.7 || !resumed.Actors[0].IsAdressed("Hey Foxie!") {
+		t.Errorf("actors were not restored: %+v", resumed.Actors)
+	}
+}
+
+func TestResumeCampaignMissingJournal(t *testing.T) {
+	_, err := ResumeCampaign(filepath.Join(t.TempDir(), "missing.yml"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected not exist error but got %v", err)
+	}
+}
+
+func TestFlushJournalWithoutPath(t *testing.T) {
+	c := testCampaign(t, "")
+	if err := c.FlushJournal(); err != nil {
+		t.Errorf("expected flush without journal to do nothing but got %v", err)
+	}
+	if err := c.removeJournal(); err != nil {
+		t.Errorf("expected remove without journal to do nothing but got %v", err)
+	}
+	if _, err := os.Stat("Test-journal.yml"); err == nil {
+		t.Error("journal should not have been written")
+	}
+}
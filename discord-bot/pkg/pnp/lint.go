@@ -0,0 +1,342 @@
+package pnp
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed common_words_de.txt
+var commonWordsDe string
+
+//go:embed common_words_en.txt
+var commonWordsEn string
+
+// commonWords by language that make bad names or aliases, as actors would be addressed by accident.
+var commonWords = map[string][]string{
+	"de": strings.Fields(commonWordsDe),
+	"en": strings.Fields(commonWordsEn),
+}
+
+var knownVoices = []openai.SpeechVoice{
+	openai.VoiceAlloy,
+	openai.VoiceEcho,
+	openai.VoiceFable,
+	openai.VoiceOnyx,
+	openai.VoiceNova,
+	openai.VoiceShimmer,
+}
+
+// discordIDPattern matches Discord user IDs, which are snowflakes of 17 to 20 digits.
+var discordIDPattern = regexp.MustCompile(`^\d{17,20}$`)
+
+// yamlErrorLinePattern matches the line of YAML syntax and type errors.
+var yamlErrorLinePattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// Problem that Lint found in a campaign file.
+type Problem struct {
+	// Line and Column of the problem in the file. Zero if the problem can't be located.
+	Line   int
+	Column int
+	// Error is true if the campaign can't be used like this. Otherwise the problem is a warning about a likely mistake.
+	Error   bool
+	Message string
+}
+
+// String renders the problem like "12:5: error: unknown voice "nvoa"".
+func (p Problem) String() string {
+	severity := "warning"
+	if p.Error {
+		severity = "error"
+	}
+	if p.Line == 0 {
+		return severity + ": " + p.Message
+	}
+	if p.Column == 0 {
+		return fmt.Sprintf("%d: %s: %s", p.Line, severity, p.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", p.Line, p.Column, severity, p.Message)
+}
+
+// HasErrors returns true if any of the problems is an error.
+func HasErrors(problems []Problem) bool {
+	return slices.ContainsFunc(problems, func(p Problem) bool { return p.Error })
+}
+
+// Lint the campaign YAML data and return all problems ordered by line. An empty result means the campaign can be used as it is.
+// language is the spoken language of the sessions like "de" and is used to find names and aliases that are common words.
+// Use "auto" to check all known languages.
+func Lint(data []byte, language string) []Problem {
+	l := &linter{language: language, owners: make(map[string]string), prompts: defaultPrompts()}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		l.yamlError(err)
+		return l.problems
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		l.errorf(&root, "campaign must be a mapping with at least a name and actors")
+		return l.problems
+	}
+	doc := root.Content[0]
+
+	if name := mappingValue(doc, "name"); name == nil || strings.TrimSpace(name.Value) == "" {
+		l.errorf(doc, "campaign has no name")
+	}
+	if players := mappingValue(doc, "players"); players != nil && players.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(players.Content); i += 2 {
+			if !discordIDPattern.MatchString(players.Content[i].Value) {
+				l.warnf(players.Content[i], "player %q is not mapped by Discord user ID but by %q", players.Content[i+1].Value, players.Content[i].Value)
+			}
+		}
+	}
+	l.usePrompts(doc)
+	actors := mappingValue(doc, "actors")
+	if actors == nil || len(actors.Content) == 0 {
+		l.warnf(doc, "campaign has no actors")
+	} else {
+		for _, actor := range actors.Content {
+			l.actor(actor)
+		}
+	}
+	if narrator := mappingValue(doc, "narrator"); narrator != nil {
+		l.narrator(narrator)
+	}
+	for key, config := range map[string]interface{ Validate() error }{
+		"turnPolicy":     &TurnPolicyConfig{},
+		"rollingSummary": &RollingSummaryConfig{},
+		"interruption":   &InterruptionConfig{},
+		"dialogue":       &DialogueConfig{},
+		"classifier":     &ClassifierConfig{},
+		"correction":     &CorrectionConfig{},
+		"prompts":        &Prompts{},
+	} {
+		node := mappingValue(doc, key)
+		if node == nil {
+			continue
+		}
+		if err := node.Decode(config); err != nil {
+			l.yamlError(err)
+			continue
+		}
+		if err := config.Validate(); err != nil {
+			l.errorf(node, "invalid %s: %v", key, err)
+		}
+	}
+
+	if !HasErrors(l.problems) {
+		// Everything that can be located is fine, so only errors across sections are left
+		c, err := CampaignFromYaml(data)
+		if err != nil {
+			l.errorf(doc, "%v", err)
+		} else {
+			c.stop()
+			// The glossary is ranked without looking up earlier sessions
+			c.dbClient = nil
+			if _, err := c.STTPrompt(); err != nil {
+				l.errorf(doc, "STT prompt can not be created: %v", err)
+			}
+		}
+	}
+	slices.SortStableFunc(l.problems, func(a, b Problem) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+	return l.problems
+}
+
+type linter struct {
+	language string
+	problems []Problem
+	// actors by name in the order they were linted.
+	actors []string
+	// owners of every name part and alias by the name of the actor that uses it first.
+	owners map[string]string
+	// prompts of the campaign and the data they are executed with to check the system prompt of every actor.
+	prompts    *prompts
+	promptData CampaignPromptData
+}
+
+func (l *linter) errorf(node *yaml.Node, format string, args ...any) {
+	l.problems = append(l.problems, Problem{Line: node.Line, Column: node.Column, Error: true, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) warnf(node *yaml.Node, format string, args ...any) {
+	l.problems = append(l.problems, Problem{Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+// yamlError adds a problem for every line that the YAML syntax or type error mentions.
+func (l *linter) yamlError(err error) {
+	var typeErr *yaml.TypeError
+	messages := []string{err.Error()}
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+	for _, msg := range messages {
+		problem := Problem{Error: true, Message: msg}
+		if match := yamlErrorLinePattern.FindStringSubmatch(strings.TrimSpace(msg)); match != nil {
+			problem.Line, _ = strconv.Atoi(match[1])
+			problem.Message = match[2]
+		}
+		l.problems = append(l.problems, problem)
+	}
+}
+
+// usePrompts of the campaign to check the system prompts of the actors. Overrides that can't be used are reported
+// with the other configs and replaced by the defaults here.
+func (l *linter) usePrompts(doc *yaml.Node) {
+	var tmp struct {
+		Name       string            `yaml:"name"`
+		Players    map[string]string `yaml:"players"`
+		Characters map[string]string `yaml:"characters"`
+		Actors     []struct {
+			Name string `yaml:"name"`
+		} `yaml:"actors"`
+		Scene    string         `yaml:"scene"`
+		Glossary []GlossaryTerm `yaml:"glossary"`
+		Prompts  Prompts        `yaml:"prompts"`
+	}
+	// Errors are reported where they can be located, everything that could be decoded is still checked
+	doc.Decode(&tmp)
+	l.prompts, _ = tmp.Prompts.parse()
+	c := Campaign{
+		Name:       tmp.Name,
+		Players:    tmp.Players,
+		Characters: tmp.Characters,
+		Scene:      tmp.Scene,
+		Glossary:   tmp.Glossary,
+	}
+	for _, actor := range tmp.Actors {
+		c.Actors = append(c.Actors, &Actor{Name: actor.Name})
+	}
+	l.promptData = c.promptData()
+}
+
+// decode the actor node. Returns false if it is invalid.
+func (l *linter) decode(node *yaml.Node, actor *Actor) bool {
+	if err := node.Decode(actor); err != nil {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			l.yamlError(err)
+		} else {
+			l.errorf(node, "%v", err)
+		}
+		return false
+	}
+	return true
+}
+
+// voice of the actor or narrator must be one of the known voices.
+func (l *linter) voice(node *yaml.Node, kind string, actor Actor) {
+	voiceNode := mappingValue(node, "voice")
+	if voiceNode == nil {
+		l.errorf(node, "%s %q has no voice", kind, actor.Name)
+	} else if !slices.Contains(knownVoices, actor.Voice) {
+		l.errorf(voiceNode, "%s %q has unknown voice %q, use one of %v", kind, actor.Name, actor.Voice, knownVoices)
+	}
+}
+
+func (l *linter) narrator(node *yaml.Node) {
+	var narrator Actor
+	if l.decode(node, &narrator) {
+		l.voice(node, "narrator", narrator)
+	}
+}
+
+func (l *linter) actor(node *yaml.Node) {
+	var actor Actor
+	if !l.decode(node, &actor) {
+		return
+	}
+	nameNode := mappingValue(node, "name")
+	if nameNode == nil || strings.TrimSpace(actor.Name) == "" {
+		l.errorf(node, "actor has no name")
+		return
+	}
+
+	l.voice(node, "actor", actor)
+	if strings.TrimSpace(actor.Script) == "" {
+		scriptNode := mappingValue(node, "script")
+		if scriptNode == nil {
+			scriptNode = node
+		}
+		l.warnf(scriptNode, "actor %q has an empty script", actor.Name)
+	}
+
+	if slices.Contains(l.actors, actor.Name) {
+		l.errorf(nameNode, "duplicate actor %q, names must be unique", actor.Name)
+	}
+	l.actors = append(l.actors, actor.Name)
+	for _, part := range strings.Split(actor.Name, " ") {
+		l.word(nameNode, actor.Name, "name part", part)
+	}
+	if aliases := mappingValue(node, "aliases"); aliases != nil {
+		for _, alias := range aliases.Content {
+			l.word(alias, actor.Name, "alias", alias.Value)
+		}
+	}
+
+	if err := l.prompts.npcSystem.Execute(io.Discard, ActorPromptData{Actor: &actor, Campaign: l.promptData}); err != nil {
+		l.errorf(node, "system prompt of actor %q can not be created: %v", actor.Name, err)
+	}
+}
+
+// word checks a name part or alias that actors are addressed with for collisions with other actors and common words.
+func (l *linter) word(node *yaml.Node, actor, kind, word string) {
+	word = strings.ToLower(removeNonWordRunes(word))
+	if word == "" {
+		return
+	}
+	if owner, ok := l.owners[word]; ok && owner != actor {
+		l.warnf(node, "%s %q of %q is also used by %q, so it is ambiguous who is addressed", kind, word, actor, owner)
+	} else if !ok {
+		l.owners[word] = actor
+	}
+	languages := make([]string, 0, len(commonWords))
+	for language := range commonWords {
+		languages = append(languages, language)
+	}
+	slices.Sort(languages)
+	for _, language := range languages {
+		if (l.language == language || l.language == "auto") && slices.Contains(commonWords[language], word) {
+			l.warnf(node, "%s %q of %q is a common word in %q, so %q would be addressed by accident", kind, word, actor, language, actor)
+		}
+	}
+}
+
+// mappingValue of the key in the mapping node or nil if it is missing.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// lintError joins the errors among the problems, so they can be returned where a single error is expected.
+func lintError(problems []Problem) error {
+	var buf bytes.Buffer
+	for _, p := range problems {
+		if p.Error {
+			fmt.Fprintln(&buf, p)
+		}
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	return errors.New(strings.TrimSpace(buf.String()))
+}
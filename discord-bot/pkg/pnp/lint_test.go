@@ -0,0 +1,98 @@
+package pnp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const lintCampaignYaml = `name: Test
+players:
+  "123456789012345678": Tharkhan
+  Lyra: Lyra
+actors:
+  - name: Petra Gabriel
+    aliases:
+      - Foxie
+    voice: nvoa
+    script: Du bist Petra.
+  - name: Gabriel
+    aliases:
+      - der
+    voice: onyx
+    script: ""
+  - name: Petra Gabriel
+    voice: nova
+    script: Du bist noch eine Petra.
+`
+
+func TestLint(t *testing.T) {
+	problems := Lint([]byte(lintCampaignYaml), "de")
+	var rendered []string
+	for _, p := range problems {
+		rendered = append(rendered, p.String())
+	}
+	expected := []string{
+		`4:3: warning: player "Lyra" is not mapped by Discord user ID but by "Lyra"`,
+		`9:12: error: actor "Petra Gabriel" has unknown voice "nvoa"`,
+		`11:11: warning: name part "gabriel" of "Gabriel" is also used by "Petra Gabriel"`,
+		`13:9: warning: alias "der" of "Gabriel" is a common word in "de"`,
+		`15:13: warning: actor "Gabriel" has an empty script`,
+		`16:11: error: duplicate actor "Petra Gabriel"`,
+	}
+	if len(rendered) != len(expected) {
+		t.Fatalf("expected %d problems but got:\n%s", len(expected), strings.Join(rendered, "\n"))
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(rendered[i], prefix) {
+			t.Errorf("expected problem %d to start with\n%s\nbut got\n%s", i, prefix, rendered[i])
+		}
+	}
+	if !HasErrors(problems) {
+		t.Error("expected errors to be reported")
+	}
+}
+
+func TestLintReportsLineOfInvalidValues(t *testing.T) {
+	tests := map[string]string{
+		"syntax":         "name: Test\nactors:\n  - name: [Brom\n",
+		"type":           "name: Test\nactors:\n  - name: Brom\n    voice: onyx\n    temperature: hot\n",
+		"invalid config": "name: Test\nactors:\n  - name: Brom\n    voice: onyx\ndialogue:\n  maxChain: -1\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			problems := Lint([]byte(data), "")
+			if !HasErrors(problems) {
+				t.Fatalf("expected an error but got %v", problems)
+			}
+			if problems[0].Line == 0 {
+				t.Errorf("expected the error to be located but got %q", problems[0])
+			}
+		})
+	}
+}
+
+func TestLintReportsLineOfActorSystemPrompt(t *testing.T) {
+	data := `name: Test
+prompts:
+  npcSystem: "You are {{ .Name }}, also known as {{ index .Aliases 1 }}."
+actors:
+  - name: Brom
+    aliases: [Bart, Bärchen]
+    voice: onyx
+    script: Du bist Brom.
+  - name: Lyra
+    voice: nova
+    script: Du bist Lyra.
+`
+	problems := Lint([]byte(data), "")
+	if len(problems) != 1 || !problems[0].Error || problems[0].Line != 9 || !strings.Contains(problems[0].Message, `system prompt of actor "Lyra"`) {
+		t.Errorf("expected the system prompt error at the actor node but got %v", problems)
+	}
+}
+
+func TestLintValidCampaign(t *testing.T) {
+	if problems := Lint([]byte(testCampaignYamlWith(t, fmt.Sprintf(dialogueCampaignYaml, 3, 6))), "en"); HasErrors(problems) {
+		t.Errorf("expected no errors but got %v", problems)
+	}
+}
@@ -0,0 +1,64 @@
+package pnp
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/vecdb"
+)
+
+// LoreCollection is the name of the vector DB collection that holds the lore of the campaign.
+func (c *Campaign) LoreCollection() string {
+	return c.Name + "Lore"
+}
+
+// IngestLore stores all lore files of the campaign in the vector DB. Files are only stored again if their content changed.
+// Files that are no longer part of the campaign will be removed from the vector DB.
+func (c *Campaign) IngestLore() error {
+	if c.dbClient == nil {
+		return errors.New("no vector DB client available")
+	}
+	hashes, err := c.dbClient.DocumentHashes(c.LoreCollection())
+	if err != nil {
+		return fmt.Errorf("could not read stored lore: %w", err)
+	}
+	for _, path := range c.Lore {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("could not read lore file: %w", err)
+		}
+		text := string(data)
+		if hash, ok := hashes[path]; ok && hash == vecdb.ContentHash(text) {
+			delete(hashes, path)
+			continue
+		}
+		slog.Info("ingesting lore file", "campaign", c.Name, "path", path)
+		if err := c.dbClient.StoreDocument(c.LoreCollection(), path, text); err != nil {
+			return fmt.Errorf("could not store lore file %s: %w", path, err)
+		}
+		c.recordEmbedding(text)
+		delete(hashes, path)
+	}
+	for path := range hashes {
+		slog.Info("removing lore file that is no longer part of the campaign", "campaign", c.Name, "path", path)
+		if err := c.dbClient.DeleteDocument(c.LoreCollection(), path); err != nil {
+			return fmt.Errorf("could not remove lore file %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// searchLore that is relevant for the concept. Returns nothing if the campaign has no lore.
+func (c *Campaign) searchLore(concept string) []string {
+	if c.dbClient == nil || len(c.Lore) == 0 {
+		return nil
+	}
+	c.recordEmbedding(concept)
+	lore, err := c.dbClient.SearchDocuments(c.LoreCollection(), concept)
+	if err != nil {
+		slog.Warn("could not search lore for reference", "error", err, "collection", c.LoreCollection(), "concept", concept)
+	}
+	return lore
+}
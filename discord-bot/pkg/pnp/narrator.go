@@ -0,0 +1,136 @@
+package pnp
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+)
+
+//go:embed recap_prompt.tpl
+var recapPromptText string
+
+var recapPromptTemplate *template.Template
+
+func init() {
+	var err error
+	recapPromptTemplate, err = template.New("recap").Parse(recapPromptText)
+	if err != nil {
+		panic(fmt.Errorf("could not parse recap prompt template: %w", err))
+	}
+	if err := checkNPCSystemTemplate(recapPromptTemplate); err != nil {
+		panic(fmt.Errorf("recap prompt template can not be executed: %w", err))
+	}
+}
+
+// recapChunks of the stored transcript of the last session that are recapped if it is not in the chronicle.
+const recapChunks = 3
+
+// defaultNarrator that recaps the story if the campaign has no narrator.
+var defaultNarrator = &Actor{Name: "Narrator"}
+
+// CurrentNarrator of the campaign or nil if it has none.
+func (c *Campaign) CurrentNarrator() *Actor {
+	c.castMu.RLock()
+	defer c.castMu.RUnlock()
+	return c.Narrator
+}
+
+// Recap what happened in the last session and in the running session so far, so the narrator can speak it at the
+// start of a session. The last session is read from the chronicle of the campaign or, if it has not been summarized,
+// from the latest transcripts in the vector DB.
+func (c *Campaign) Recap() (string, error) {
+	lastSession, err := c.lastSession()
+	if err != nil {
+		return "", err
+	}
+	var material strings.Builder
+	if lastSession != "" {
+		fmt.Fprintf(&material, "- LAST SESSION -\n%s\n\n", strings.TrimSpace(lastSession))
+	}
+	if transcript := c.Classifier.render(c.Transcript.Entries(), c.Classifier.Summaries); transcript != "" {
+		fmt.Fprintf(&material, "- CURRENT SESSION -\n%s\n", transcript)
+	}
+	if material.Len() == 0 {
+		return "", errors.New("nothing happened yet that could be recapped")
+	}
+
+	c.castMu.RLock()
+	narrator := c.Narrator
+	if narrator == nil {
+		narrator = defaultNarrator
+	}
+	systemPromptBuf := bytes.NewBuffer(make([]byte, 0))
+	err = c.prompts.recap.Execute(systemPromptBuf, ActorPromptData{Actor: narrator, Campaign: c.promptData()})
+	c.castMu.RUnlock()
+	if err != nil {
+		return "", fmt.Errorf("could not resolve recap prompt template: %w", err)
+	}
+	narrator, ok := c.applyBudget(narrator)
+	if !ok {
+		return "", errors.New("hard budget limit reached")
+	}
+	resp, err := c.model(UsageNarrator).Chat(context.Background(), llm.Request{
+		Model:           narrator.Model,
+		MaxTokens:       narrator.MaxTokens,
+		Temperature:     narrator.Temperature,
+		TopP:            narrator.TopP,
+		PresencePenalty: narrator.PresencePenalty,
+		Stop:            narrator.Stop,
+		Messages: []llm.Message{
+			{
+				Role:    llm.RoleSystem,
+				Content: systemPromptBuf.String(),
+			},
+			{
+				Role:    llm.RoleUser,
+				Content: material.String(),
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Content), nil
+}
+
+// lastSession before the running one from the chronicle or the stored transcripts. Empty if there is none.
+func (c *Campaign) lastSession() (string, error) {
+	if c.dbClient == nil {
+		return "", nil
+	}
+	sessions, err := c.dbClient.DocumentHashes(c.ChronicleCollection())
+	if err != nil {
+		return "", fmt.Errorf("could not read chronicle: %w", err)
+	}
+	var last time.Time
+	var lastSource string
+	for source := range sessions {
+		startedAt, err := time.Parse(time.RFC3339, source)
+		if err != nil || !startedAt.Before(c.StartedAt.Truncate(time.Second)) {
+			continue
+		}
+		if startedAt.After(last) {
+			last = startedAt
+			lastSource = source
+		}
+	}
+	if lastSource != "" {
+		summary, err := c.dbClient.ReadDocument(c.ChronicleCollection(), lastSource)
+		if err != nil {
+			return "", fmt.Errorf("could not read summary of last session: %w", err)
+		}
+		return summary, nil
+	}
+	chunks, err := c.dbClient.LatestChunks(c.Name, recapChunks)
+	if err != nil {
+		return "", fmt.Errorf("could not read transcripts of last session: %w", err)
+	}
+	return strings.Join(chunks, "\n"), nil
+}
@@ -0,0 +1,48 @@
+package pnp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const narratorCampaignYaml = `narrator:
+  name: Erzähler
+  voice: fable
+  script: Du erzählst wie ein Märchenonkel.
+`
+
+func TestRecap(t *testing.T) {
+	var received openai.ChatCompletionRequest
+	c := campaignFromYaml(t, narratorCampaignYaml, " Zuvor bei Test: Tharkhan betrat die Taverne. ", &received)
+
+	if _, err := c.Recap(); err == nil {
+		t.Error("expected an empty session without chronicle to have nothing to recap")
+	}
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Ich betrete die Taverne."})
+	recap, err := c.Recap()
+	if err != nil {
+		t.Fatalf("could not recap: %v", err)
+	}
+	if recap != "Zuvor bei Test: Tharkhan betrat die Taverne." {
+		t.Errorf("unexpected recap %q", recap)
+	}
+	if system := received.Messages[0].Content; !strings.HasPrefix(system, "You are Erzähler, the narrator") || !strings.Contains(system, "Märchenonkel") {
+		t.Errorf("expected the narrator in the system prompt but got:\n%s", system)
+	}
+	if user := received.Messages[1].Content; user != "- CURRENT SESSION -\nTharkhan: Ich betrete die Taverne.\n" {
+		t.Errorf("unexpected recap material:\n%s", user)
+	}
+	if report, _ := c.Usage(); report.Current().Actors[UsageNarrator].Total().PromptTokens == 0 {
+		t.Error("expected the usage of the narrator to be recorded")
+	}
+}
+
+func TestLintNarrator(t *testing.T) {
+	problems := Lint([]byte(testCampaignYamlWith(t, strings.Replace(narratorCampaignYaml, "voice: fable", "voice: fabel", 1))), "")
+	if len(problems) != 2 || !strings.HasPrefix(problems[1].String(), `12:12: error: narrator "Erzähler" has unknown voice "fabel"`) {
+		t.Errorf("expected the unknown voice of the narrator to be reported but got %v", problems)
+	}
+}
@@ -4,13 +4,14 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
 	"io"
 	"slices"
 	"strings"
 	"text/template"
 
-	"github.com/MrWong99/TaileVoices/discord_bot/pkg/oai"
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
 	"github.com/sashabaranov/go-openai"
 	"gopkg.in/yaml.v3"
 )
@@ -32,14 +33,7 @@ func init() {
 	if err != nil {
 		panic(fmt.Errorf("could not parse NPC system prompt template: %w", err))
 	}
-	sampleActor := Actor{
-		Name:    "Test Me",
-		Aliases: []string{"foo", "bar"},
-		Script: `A script
-		with some lines`,
-	}
-	err = npcSystemPromptTemplate.Execute(io.Discard, sampleActor)
-	if err != nil {
+	if err := checkNPCSystemTemplate(npcSystemPromptTemplate); err != nil {
 		panic(fmt.Errorf("NPC system prompt template can not be executed: %w", err))
 	}
 
@@ -48,13 +42,7 @@ func init() {
 	if err != nil {
 		panic(fmt.Errorf("could not parse NPC user prompt template: %w", err))
 	}
-	c := PromptContext{
-		OldTranscripts: []string{"Test: Hello, World!\nGameMaster: Be quiet...", "Foo: Bar!"},
-		CurrentTranscript: `User 1: Hello
-		User 2: World!`,
-	}
-	err = npcUserPromptTemplate.Execute(io.Discard, c)
-	if err != nil {
+	if err := checkNPCUserTemplate(npcUserPromptTemplate); err != nil {
 		panic(fmt.Errorf("NPC user prompt template can not be executed: %w", err))
 	}
 }
@@ -68,16 +56,67 @@ type Actor struct {
 	// Script that the actor should follow. Should include info about his behaviour, the pen and paper world setting and all other characters he knows.
 	Script string `yaml:"script"`
 	// Voice that this actor should use when speaking.
-	Voice           openai.SpeechVoice `yaml:"voice"`
+	Voice openai.SpeechVoice `yaml:"voice"`
+	// GenerationParams to tune the responses of this actor.
+	GenerationParams `yaml:",inline"`
+	// Budget that limits how much transcript text this actor sees per response.
+	Budget PromptBudget `yaml:"budget"`
+	// Tools that the actor may call while responding like "roll_dice" or "give_item".
+	Tools           []string `yaml:"tools"`
 	namesAndAliases []string
 	systemPrompt    string
+	userPrompt      *template.Template
+	promptData      CampaignPromptData
+}
+
+// GenerationParams to tune how an actor generates responses. Zero values will use the defaults of the chat model.
+type GenerationParams struct {
+	// Model that should be used instead of the campaigns default model. Cheap models are fine for background NPCs.
+	Model string `yaml:"model,omitempty"`
+	// Temperature between 0 and 2 if set. Higher values make responses more random, 0 makes them almost deterministic.
+	Temperature *float32 `yaml:"temperature,omitempty"`
+	// MaxTokens that a single response may have.
+	MaxTokens int `yaml:"maxTokens,omitempty"`
+	// TopP between 0 and 1 for nucleus sampling.
+	TopP float32 `yaml:"topP,omitempty"`
+	// PresencePenalty between -2 and 2. Positive values make the actor more likely to talk about new topics.
+	PresencePenalty float32 `yaml:"presencePenalty,omitempty"`
+	// Stop sequences that end a response. At most 4 are allowed.
+	Stop []string `yaml:"stop,omitempty"`
+}
+
+// Validate that all parameters are within their allowed ranges.
+func (p GenerationParams) Validate() error {
+	var err error
+	if p.Temperature != nil && (*p.Temperature < 0 || *p.Temperature > 2) {
+		err = errors.Join(err, fmt.Errorf("temperature must be between 0 and 2 but is %v", *p.Temperature))
+	}
+	if p.MaxTokens < 0 {
+		err = errors.Join(err, fmt.Errorf("maxTokens must not be negative but is %d", p.MaxTokens))
+	}
+	if p.TopP < 0 || p.TopP > 1 {
+		err = errors.Join(err, fmt.Errorf("topP must be between 0 and 1 but is %v", p.TopP))
+	}
+	if p.PresencePenalty < -2 || p.PresencePenalty > 2 {
+		err = errors.Join(err, fmt.Errorf("presencePenalty must be between -2 and 2 but is %v", p.PresencePenalty))
+	}
+	if len(p.Stop) > 4 {
+		err = errors.Join(err, fmt.Errorf("at most 4 stop sequences are allowed but got %d", len(p.Stop)))
+	}
+	if slices.Contains(p.Stop, "") {
+		err = errors.Join(err, errors.New("stop sequences must not be empty"))
+	}
+	return err
 }
 
 type tmpActor struct {
-	Name    string             `yaml:"name"`
-	Aliases []string           `yaml:"aliases"`
-	Script  string             `yaml:"script"`
-	Voice   openai.SpeechVoice `yaml:"voice"`
+	Name             string             `yaml:"name"`
+	Aliases          []string           `yaml:"aliases,omitempty"`
+	Script           string             `yaml:"script"`
+	Voice            openai.SpeechVoice `yaml:"voice"`
+	GenerationParams `yaml:",inline"`
+	Budget           PromptBudget `yaml:"budget,omitempty"`
+	Tools            []string     `yaml:"tools,omitempty"`
 }
 
 // UnmarshalYAML implements the unmarshalling including the required initialization.
@@ -92,10 +131,35 @@ func (a *Actor) UnmarshalYAML(value *yaml.Node) error {
 	a.Aliases = tmp.Aliases
 	a.Script = tmp.Script
 	a.Voice = tmp.Voice
+	if err := tmp.GenerationParams.Validate(); err != nil {
+		return fmt.Errorf("invalid generation parameters for actor %q: %w", tmp.Name, err)
+	}
+	a.GenerationParams = tmp.GenerationParams
+	if err := tmp.Budget.Validate(); err != nil {
+		return fmt.Errorf("invalid budget for actor %q: %w", tmp.Name, err)
+	}
+	a.Budget = tmp.Budget
+	if err := validateTools(tmp.Tools); err != nil {
+		return fmt.Errorf("invalid tools for actor %q: %w", tmp.Name, err)
+	}
+	a.Tools = tmp.Tools
 	a.init()
 	return nil
 }
 
+// MarshalYAML stores the actor in the same format that UnmarshalYAML reads.
+func (a *Actor) MarshalYAML() (any, error) {
+	return tmpActor{
+		Name:             a.Name,
+		Aliases:          a.Aliases,
+		Script:           a.Script,
+		Voice:            a.Voice,
+		GenerationParams: a.GenerationParams,
+		Budget:           a.Budget,
+		Tools:            a.Tools,
+	}, nil
+}
+
 // NewActor to integrate into a campaign.
 func NewActor(name, script string, voice openai.SpeechVoice, aliases ...string) *Actor {
 	a := Actor{
@@ -122,43 +186,171 @@ func (a *Actor) init() {
 		a.namesAndAliases[i] = strings.ToLower(cleanPart)
 		i++
 	}
-	systemPromptBuf := bytes.NewBuffer(make([]byte, 0))
-	if err := npcSystemPromptTemplate.Execute(systemPromptBuf, a); err != nil {
+	if err := a.usePrompts(defaultPrompts(), CampaignPromptData{}); err != nil {
 		// should not be possible as sanity check was done in init func
 		panic(err)
 	}
+}
+
+// usePrompts of a campaign for all further responses of the actor.
+func (a *Actor) usePrompts(p *prompts, data CampaignPromptData) error {
+	systemPromptBuf := bytes.NewBuffer(make([]byte, 0))
+	if err := p.npcSystem.Execute(systemPromptBuf, ActorPromptData{Actor: a, Campaign: data}); err != nil {
+		return err
+	}
 	a.systemPrompt = systemPromptBuf.String()
+	a.userPrompt = p.npcUser
+	a.promptData = data
+	return nil
 }
 
 type PromptContext struct {
-	OldTranscripts    []string
+	// Lore that is relevant for the current transcript ordered by relevance. Can be empty.
+	Lore           []string
+	OldTranscripts []string
+	// StorySoFar summarizes the earlier parts of the current session. Can be empty.
+	StorySoFar        string
 	CurrentTranscript string
+	// Direction of the GM how the actor should respond. Can be empty.
+	Direction string
 }
 
-// Act with the given prompt context. Try to keep the sum of text from all old transcripts plus the current transcript below a certain amount.
-func (a *Actor) Act(ctx PromptContext) (string, error) {
+// Act with the given prompt context using the chat model. Use the actors Budget to fit the prompt context beforehand.
+// If toolbox is not nil the actor may call its tools for up to maxToolRounds before it has to answer.
+func (a *Actor) Act(ctx context.Context, model llm.ChatModel, prompt PromptContext, toolbox Toolbox) (string, error) {
+	req, err := a.chatRequest(prompt)
+	if err != nil {
+		return "", err
+	}
+	tools := a.tools(toolbox)
+	for round := 0; ; round++ {
+		req.Tools = nil
+		if round < maxToolRounds {
+			req.Tools = tools
+		}
+		resp, err := model.Chat(ctx, req)
+		if err != nil {
+			return "", err
+		}
+		if len(resp.ToolCalls) == 0 || req.Tools == nil {
+			return resp.Content, nil
+		}
+		req.Messages = a.callTools(ctx, toolbox, req.Messages, resp.Content, resp.ToolCalls)
+	}
+}
+
+// ActStream works like Act but streams the response. onSentence is called for every complete sentence as soon as it was generated.
+// Returns the full response once the stream has ended. If an error occurs midway the text generated so far is returned with the error.
+// The stream stops as soon as ctx is done.
+func (a *Actor) ActStream(ctx context.Context, model llm.ChatModel, prompt PromptContext, toolbox Toolbox, onSentence func(sentence string)) (string, error) {
+	req, err := a.chatRequest(prompt)
+	if err != nil {
+		return "", err
+	}
+	tools := a.tools(toolbox)
+	var spoken []string
+	for round := 0; ; round++ {
+		req.Tools = nil
+		if round < maxToolRounds {
+			req.Tools = tools
+		}
+		content, toolCalls, err := streamSentences(ctx, model, req, onSentence)
+		if content != "" {
+			spoken = append(spoken, content)
+		}
+		if err != nil || len(toolCalls) == 0 || req.Tools == nil {
+			return strings.Join(spoken, " "), err
+		}
+		req.Messages = a.callTools(ctx, toolbox, req.Messages, content, toolCalls)
+	}
+}
+
+// streamSentences of a single chat request. Returns the streamed content and the tool calls that the model requested.
+func streamSentences(ctx context.Context, model llm.ChatModel, req llm.Request, onSentence func(sentence string)) (string, []llm.ToolCall, error) {
+	stream, err := model.ChatStream(ctx, req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer stream.Close()
+
+	var full strings.Builder
+	var splitter sentenceSplitter
+	for {
+		delta, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return full.String(), nil, err
+		}
+		full.WriteString(delta)
+		for _, sentence := range splitter.Write(delta) {
+			onSentence(sentence)
+		}
+	}
+	if rest := splitter.Flush(); rest != "" {
+		onSentence(rest)
+	}
+	return full.String(), stream.ToolCalls(), nil
+}
+
+func (a *Actor) tools(toolbox Toolbox) []llm.Tool {
+	if toolbox == nil {
+		return nil
+	}
+	tools := toolbox.Tools(a)
+	if len(tools) == 0 {
+		return nil
+	}
+	return tools
+}
+
+// callTools of the toolbox and append the assistant message as well as the results to the messages.
+// Failed calls are reported to the model so it can react to them.
+func (a *Actor) callTools(ctx context.Context, toolbox Toolbox, messages []llm.Message, content string, toolCalls []llm.ToolCall) []llm.Message {
+	messages = append(messages, llm.Message{
+		Role:      llm.RoleAssistant,
+		Content:   content,
+		ToolCalls: toolCalls,
+	})
+	for _, call := range toolCalls {
+		result, err := toolbox.Call(ctx, a, call)
+		if err != nil {
+			result = "error: " + err.Error()
+		}
+		messages = append(messages, llm.Message{
+			Role:       llm.RoleTool,
+			Content:    result,
+			ToolCallID: call.ID,
+		})
+	}
+	return messages
+}
+
+func (a *Actor) chatRequest(prompt PromptContext) (llm.Request, error) {
 	userPromptBuf := bytes.NewBuffer(make([]byte, 0))
-	err := npcUserPromptTemplate.Execute(userPromptBuf, ctx)
+	err := a.userPrompt.Execute(userPromptBuf, UserPromptData{PromptContext: prompt, Campaign: a.promptData})
 	if err != nil {
-		return "", fmt.Errorf("could not resolve user prompt template: %w", err)
+		return llm.Request{}, fmt.Errorf("could not resolve user prompt template: %w", err)
 	}
-	resp, err := oai.Client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
-		Model: openai.GPT4o,
-		Messages: []openai.ChatCompletionMessage{
+	return llm.Request{
+		Model:           a.Model,
+		MaxTokens:       a.MaxTokens,
+		Temperature:     a.Temperature,
+		TopP:            a.TopP,
+		PresencePenalty: a.PresencePenalty,
+		Stop:            a.Stop,
+		Messages: []llm.Message{
 			{
-				Role:    openai.ChatMessageRoleSystem,
+				Role:    llm.RoleSystem,
 				Content: a.systemPrompt,
 			},
 			{
-				Role:    openai.ChatMessageRoleUser,
+				Role:    llm.RoleUser,
 				Content: userPromptBuf.String(),
 			},
 		},
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to create chat completion: %w", err)
-	}
-	return resp.Choices[0].Message.Content, nil
+	}, nil
 }
 
 // IsAdressed will return true if the actors name or any of his aliases is included in the given line of text.
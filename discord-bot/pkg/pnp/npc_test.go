@@ -2,8 +2,12 @@ package pnp
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"gopkg.in/yaml.v3"
 )
 
 func TestActorSystemPromptTemplate(t *testing.T) {
@@ -37,27 +41,43 @@ func systemTemplateByHand(name, script string, aliases ...string) string {
 ` + aliasText + `
 
 You will perceive the world thorugh two sources: first a possibly empty list of older transcripts and second a transcript of the current pen and paper session.
-The transcripts are not perfect so try to deduce some context or fix the spelling or language if needed.
+Sometimes they will be preceded by lore about the world that you can use as background knowledge.
+If the current session is already running for a while its earlier parts will be given to you as a summary of the story so far.
+The transcripts are not perfect so try to deduce some context or fix the spelling or grammar if needed.
+Lines like "Name (tool): ..." show tools that were used and their results. They are not spoken out loud.
 
 The transcripts will be provided by the user in the following format delimited by """:
 """
+- LORE -
+knowledge about the world
+...
+
 - OLD TRANSCRIPTS -
-1:
+0:
 Name: text line
 Other Name: text line
 ...
 
-2:
+1:
 ...
 
 
+- STORY SO FAR -
+1. summary of what happened
+2. ...
+
 - CURRENT TRANSCRIPT -
 Name: text line
 Other name: text line
 ...
 """
 
+Sometimes the game master gives you a direction after the transcripts, preceded by "- GM DIRECTION -". Follow it with your next line.
+
 Your answers should be responses in natural language that fit into the end of the current transcript.
+Omit your name at the beginning of the line so instead of "Name: My response" just respond "My response".
+Also never include lines of other speakers, just speak your next line and nothing more!
+Always answer in the same language as the current transcript!
 Keep your answers short unless the following script tells you otherwise.
 
 This is your script that you must follow at all times unless any of the transcripts suggest a different approach:
@@ -126,3 +146,80 @@ func userTemplateByHand(currentTranscript string, oldTranscripts ...string) stri
 ` + currentTranscript + `
 """`
 }
+
+// temperature to set in GenerationParams.
+func temperature(value float32) *float32 {
+	return &value
+}
+
+func TestGenerationParamsValidate(t *testing.T) {
+	valid := []GenerationParams{
+		{},
+		{Model: "gpt-4o-mini", Temperature: temperature(1.3), MaxTokens: 60, TopP: 0.9, PresencePenalty: -0.5, Stop: []string{"\n"}},
+		{Temperature: temperature(0), PresencePenalty: -2},
+		{Temperature: temperature(2), TopP: 1, PresencePenalty: 2},
+	}
+	invalid := []GenerationParams{
+		{Temperature: temperature(-0.1)},
+		{Temperature: temperature(2.1)},
+		{MaxTokens: -1},
+		{TopP: 1.5},
+		{PresencePenalty: -3},
+		{Stop: []string{"a", "b", "c", "d", "e"}},
+		{Stop: []string{""}},
+	}
+	for _, p := range valid {
+		if err := p.Validate(); err != nil {
+			t.Errorf("expected params %+v to be valid but got: %v", p, err)
+		}
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("expected params %+v to be invalid", p)
+		}
+	}
+}
+
+func TestActorGenerationParamsFromYaml(t *testing.T) {
+	var a Actor
+	err := yaml.Unmarshal([]byte(`name: Guard
+voice: onyx
+model: gpt-4o-mini
+temperature: 0.2
+maxTokens: 40
+topP: 0.5
+presencePenalty: 1
+stop:
+  - "Player:"
+script: You are a terse guard.`), &a)
+	if err != nil {
+		t.Fatalf("could not read actor: %v", err)
+	}
+
+	var received openai.ChatCompletionRequest
+	model := newChatStandIn(t, "Halt!", &received)
+	if _, err := a.Act(context.Background(), model, PromptContext{CurrentTranscript: "Tharkhan: Hello"}, nil); err != nil {
+		t.Fatalf("unexpected error while acting: %v", err)
+	}
+	if received.Model != "gpt-4o-mini" || received.Temperature != 0.2 || received.MaxTokens != 40 ||
+		received.TopP != 0.5 || received.PresencePenalty != 1 || len(received.Stop) != 1 || received.Stop[0] != "Player:" {
+		t.Errorf("generation params were not applied to request: %+v", received)
+	}
+
+	var cold Actor
+	if err := yaml.Unmarshal([]byte("name: Guard\nvoice: onyx\ntemperature: 0\nscript: You are a terse guard."), &cold); err != nil {
+		t.Fatalf("could not read actor: %v", err)
+	}
+	if _, err := cold.Act(context.Background(), model, PromptContext{CurrentTranscript: "Tharkhan: Hello"}, nil); err != nil {
+		t.Fatalf("unexpected error while acting: %v", err)
+	}
+	if received.Temperature == 0 || received.Temperature > 0.001 {
+		t.Errorf("expected temperature 0 to be requested instead of the default but got %v", received.Temperature)
+	}
+
+	err = yaml.Unmarshal([]byte(`name: Bard
+temperature: 3`), &a)
+	if err == nil {
+		t.Error("expected actor with temperature 3 to be rejected")
+	}
+}
@@ -1,10 +1,19 @@
 """
-- OLD TRANSCRIPTS -
+{{ if .Lore }}- LORE -
+{{ range .Lore }}{{ . }}
+
+{{ end }}{{ end }}- OLD TRANSCRIPTS -
 {{ range $index, $transcript := .OldTranscripts -}}
 {{ $index }}:
 {{ $transcript }}
 {{ end }}
-
+{{ if .StorySoFar }}
+- STORY SO FAR -
+{{ .StorySoFar }}
+{{ end }}
 - CURRENT TRANSCRIPT -
 {{ .CurrentTranscript }}
-"""
\ No newline at end of file
+"""{{ if .Direction }}
+
+- GM DIRECTION -
+{{ .Direction }}{{ end }}
\ No newline at end of file
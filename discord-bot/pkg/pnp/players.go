@@ -0,0 +1,224 @@
+package pnp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IsDiscordID returns true if the players key is a Discord user ID and not a username.
+func IsDiscordID(key string) bool {
+	return discordIDPattern.MatchString(key)
+}
+
+// PlayerName of the player with the given Discord user ID. A character that the player switched to for the running
+// session takes precedence over the registered one. The fallbacks like the username are tried in order if the user ID
+// is not registered, for campaign files whose players are still keyed by username.
+func (c *Campaign) PlayerName(userID string, fallbacks ...string) (name string, ok bool) {
+	c.castMu.RLock()
+	defer c.castMu.RUnlock()
+	if name, ok := c.Characters[userID]; ok {
+		return name, true
+	}
+	for _, key := range append([]string{userID}, fallbacks...) {
+		if name, ok := c.Players[key]; ok && key != "" {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// CurrentPlayers of the campaign by Discord user ID. The map is a copy and includes the characters that players switched
+// to for the running session.
+func (c *Campaign) CurrentPlayers() map[string]string {
+	c.castMu.RLock()
+	defer c.castMu.RUnlock()
+	players := maps.Clone(c.Players)
+	if players == nil {
+		players = make(map[string]string)
+	}
+	maps.Copy(players, c.Characters)
+	return players
+}
+
+// RegisteredCharacter of the player with the given Discord user ID, ignoring switches for the running session.
+func (c *Campaign) RegisteredCharacter(userID string) (string, bool) {
+	c.castMu.RLock()
+	defer c.castMu.RUnlock()
+	name, ok := c.Players[userID]
+	return name, ok
+}
+
+// RegisterPlayer with the given Discord user ID as playing the character. Replaces an earlier registration of the user
+// and the entry keyed by its username. Use RegisterPlayerInFile to keep the registration for the next sessions.
+func (c *Campaign) RegisterPlayer(userID, username, character string) error {
+	character, err := c.checkCharacter(character)
+	if err != nil {
+		return err
+	}
+	c.castMu.Lock()
+	defer c.castMu.Unlock()
+	players := maps.Clone(c.Players)
+	if players == nil {
+		players = make(map[string]string)
+	}
+	if username != "" {
+		delete(players, username)
+	}
+	players[userID] = character
+	c.Players = players
+	slog.Info("registered player", "campaign", c.Name, "userID", userID, "character", character)
+	return c.refreshCast()
+}
+
+// SwitchCharacter of the player with the given Discord user ID for the running session only, e.g. when a player runs two
+// characters. Switching to the registered character or to an empty one ends the switch.
+func (c *Campaign) SwitchCharacter(userID, character string) error {
+	character = strings.TrimSpace(character)
+	if character != "" {
+		var err error
+		if character, err = c.checkCharacter(character); err != nil {
+			return err
+		}
+	}
+	c.castMu.Lock()
+	defer c.castMu.Unlock()
+	characters := maps.Clone(c.Characters)
+	if characters == nil {
+		characters = make(map[string]string)
+	}
+	if character == "" || c.Players[userID] == character {
+		delete(characters, userID)
+	} else {
+		characters[userID] = character
+	}
+	c.Characters = characters
+	slog.Info("switched character for this session", "campaign", c.Name, "userID", userID, "character", character)
+	return c.refreshCast()
+}
+
+// checkCharacter name that a player wants to play. Returns the trimmed name.
+func (c *Campaign) checkCharacter(character string) (string, error) {
+	character = strings.TrimSpace(character)
+	if character == "" {
+		return "", errors.New("character must have a name")
+	}
+	if strings.ContainsAny(character, ":\n") {
+		return "", fmt.Errorf("character %q must not contain colons or line breaks", character)
+	}
+	if actor, ok := c.Actor(character); ok {
+		return "", fmt.Errorf("character %q is already played by the actor %q", character, actor.Name)
+	}
+	return character, nil
+}
+
+// refreshCast re-creates the prompts of all actors after the players changed. The actors are replaced by copies, so
+// responses that are already running keep the actor as it was. Must be called while holding castMu.
+func (c *Campaign) refreshCast() error {
+	actors := make([]*Actor, len(c.Actors))
+	for i, actor := range c.Actors {
+		clone := *actor
+		actors[i] = &clone
+	}
+	c.Actors = actors
+	return c.applyPrompts()
+}
+
+// RegisterPlayerInFile persists the registration of the player into the campaign YAML file at path like RegisterPlayer.
+// Comments and the order of everything else in the file are kept. The file is replaced at once, so readers never see
+// it half written. Returns the modification time of the written file.
+func RegisterPlayerInFile(path, userID, username, character string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return time.Time{}, fmt.Errorf("could not parse campaign file: %w", err)
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return time.Time{}, errors.New("campaign file must be a mapping")
+	}
+	doc := root.Content[0]
+	players := mappingValue(doc, "players")
+	if players == nil {
+		players = &yaml.Node{}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "players"}, players)
+	}
+	if players.Kind != yaml.MappingNode {
+		// An empty "players:" is a null scalar
+		*players = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+	content := make([]*yaml.Node, 0, len(players.Content)+2)
+	for i := 0; i+1 < len(players.Content); i += 2 {
+		key := players.Content[i].Value
+		if key == userID || (username != "" && key == username) {
+			continue
+		}
+		content = append(content, players.Content[i], players.Content[i+1])
+	}
+	players.Content = append(content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: yaml.DoubleQuotedStyle, Value: userID},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: strings.TrimSpace(character)},
+	)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&root); err != nil {
+		return time.Time{}, err
+	}
+	if err := enc.Close(); err != nil {
+		return time.Time{}, err
+	}
+	c, err := CampaignFromYaml(buf.Bytes())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("campaign would become invalid: %w", err)
+	}
+	c.stop()
+	if _, err := c.checkCharacter(character); err != nil {
+		return time.Time{}, err
+	}
+	return replaceFile(path, buf.Bytes(), info.Mode().Perm())
+}
+
+// replaceFile at path with the data by renaming a temporary file over it. Returns the modification time of the new file.
+func replaceFile(path string, data []byte, perm fs.FileMode) (time.Time, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return time.Time{}, err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return time.Time{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return time.Time{}, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
@@ -0,0 +1,112 @@
+package pnp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestPlayerName(t *testing.T) {
+	c := testCampaign(t, "")
+	c.Players["legacyuser"] = "Lyra"
+
+	if name, ok := c.PlayerName("1234", "tharkhanuser"); !ok || name != "Tharkhan" {
+		t.Errorf("expected Tharkhan by user ID but got %q", name)
+	}
+	if name, ok := c.PlayerName("5678", "legacyuser"); !ok || name != "Lyra" {
+		t.Errorf("expected Lyra by username but got %q", name)
+	}
+	if name, ok := c.PlayerName("9999", ""); ok {
+		t.Errorf("expected unknown player but got %q", name)
+	}
+}
+
+func TestRegisterAndSwitchCharacter(t *testing.T) {
+	c := testCampaign(t, "")
+	c.Players["lyrauser"] = "Lyra"
+	actor := c.Actors[0]
+
+	if err := c.RegisterPlayer("5678", "lyrauser", " Lyra "); err != nil {
+		t.Fatalf("could not register player: %v", err)
+	}
+	if _, ok := c.Players["lyrauser"]; ok || c.Players["5678"] != "Lyra" {
+		t.Errorf("expected the username entry to be replaced by the user ID but got %v", c.Players)
+	}
+	if err := c.SwitchCharacter("1234", "Borin"); err != nil {
+		t.Fatalf("could not switch character: %v", err)
+	}
+	if name, _ := c.PlayerName("1234"); name != "Borin" {
+		t.Errorf("expected the character of this session but got %q", name)
+	}
+	if name, _ := c.RegisteredCharacter("1234"); name != "Tharkhan" {
+		t.Errorf("expected the registered character to be kept but got %q", name)
+	}
+	if players := c.Actors[0].promptData.Players; strings.Join(players, ",") != "Borin,Lyra,Tharkhan" {
+		t.Errorf("expected the actors to know all characters but got %q", players)
+	}
+	if c.Actors[0] == actor || strings.Join(actor.promptData.Players, ",") != "Tharkhan" {
+		t.Error("expected actors that are already responding to be left alone")
+	}
+
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		t.Fatalf("could not marshal campaign: %v", err)
+	}
+	resumed, err := CampaignFromYaml(data)
+	if err != nil {
+		t.Fatalf("could not restore campaign: %v", err)
+	}
+	if name, _ := resumed.PlayerName("1234"); name != "Borin" {
+		t.Errorf("expected the character switch to be journaled but got %q", name)
+	}
+
+	if err := c.SwitchCharacter("1234", "Tharkhan"); err != nil {
+		t.Fatalf("could not switch back: %v", err)
+	}
+	if len(c.Characters) != 0 {
+		t.Errorf("expected switching to the registered character to end the switch but got %v", c.Characters)
+	}
+	for _, invalid := range []string{"", "petra gabriel", "Tharkhan: Hallo"} {
+		if err := c.RegisterPlayer("1234", "", invalid); err == nil {
+			t.Errorf("expected character %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestRegisterPlayerInFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test-campaign.yml")
+	original := "# The heroes of Interlumen\nname: Test\nplayers:\n  tharkhanuser: Tharkhan # the dwarf\n  \"5678\": Lyra\nactors:\n  - name: Brom\n    voice: onyx\n    script: Du bist Brom.\n"
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatalf("could not write campaign file: %v", err)
+	}
+
+	modTime, err := RegisterPlayerInFile(path, "1234", "tharkhanuser", "Tharkhan")
+	if err != nil {
+		t.Fatalf("could not register player in file: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("could not stat campaign file: %v", err)
+	}
+	if !info.ModTime().Equal(modTime) || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected modification time %v and mode 0600 but got %v and %v", modTime, info.ModTime(), info.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left but got %v", entries)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read campaign file: %v", err)
+	}
+	expected := "# The heroes of Interlumen\nname: Test\nplayers:\n  \"5678\": Lyra\n  \"1234\": Tharkhan\nactors:\n  - name: Brom\n    voice: onyx\n    script: Du bist Brom.\n"
+	if string(data) != expected {
+		t.Errorf("expected campaign file:\n%s\nbut got:\n%s", expected, data)
+	}
+
+	if _, err := RegisterPlayerInFile(path, "1234", "", "Brom"); err == nil {
+		t.Error("expected the name of an actor to be rejected")
+	}
+}
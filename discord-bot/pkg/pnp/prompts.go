@@ -0,0 +1,270 @@
+package pnp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prompts that override the embedded default prompt templates of a campaign. Overrides use Go's text/template syntax.
+type Prompts struct {
+	// NPCSystem is the system prompt of every actor. Executed with ActorPromptData.
+	NPCSystem PromptTemplate `yaml:"npcSystem,omitempty"`
+	// NPCUser presents the transcripts to the actors. Executed with UserPromptData.
+	NPCUser PromptTemplate `yaml:"npcUser,omitempty"`
+	// STTInit primes the speech recognition with the names of the session. Executed with the Campaign whose glossary
+	// only holds the recently used terms that fit into the prompt, the most recently used last.
+	STTInit PromptTemplate `yaml:"sttInit,omitempty"`
+	// Summarization instructs the summary of a finished session. Executed with CampaignPromptData.
+	Summarization PromptTemplate `yaml:"summarization,omitempty"`
+	// Recap is the system prompt of the narrator. Executed with ActorPromptData.
+	Recap PromptTemplate `yaml:"recap,omitempty"`
+	// Correction instructs the correction of the transcript after a session. Executed with CampaignPromptData.
+	Correction PromptTemplate `yaml:"correction,omitempty"`
+}
+
+// Validate that all overrides can be parsed and executed.
+func (p Prompts) Validate() error {
+	_, err := p.parse()
+	return err
+}
+
+// PromptTemplate that is given either inline as a string or by file path like:
+//
+//	npcSystem: "You are {{ .Name }}..."
+//	npcUser:
+//	  file: horror-npc-user.tpl
+type PromptTemplate struct {
+	// File that the template is read from. Relative paths are resolved against the working directory.
+	File string
+	// Text of the template. Filled from File if it is set.
+	Text string
+}
+
+// UnmarshalYAML reads the template inline or from its file.
+func (t *PromptTemplate) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		t.Text = value.Value
+		return nil
+	}
+	var tmp struct {
+		File string `yaml:"file"`
+	}
+	if err := value.Decode(&tmp); err != nil {
+		return err
+	}
+	if tmp.File == "" {
+		return errors.New("prompt template must be a string or have a file")
+	}
+	text, err := os.ReadFile(tmp.File)
+	if err != nil {
+		return fmt.Errorf("could not read prompt template: %w", err)
+	}
+	t.File = tmp.File
+	t.Text = string(text)
+	return nil
+}
+
+// MarshalYAML stores the template in the same form that it was read in, so files are read again on resume.
+func (t PromptTemplate) MarshalYAML() (any, error) {
+	if t.File != "" {
+		return map[string]string{"file": t.File}, nil
+	}
+	return t.Text, nil
+}
+
+// IsZero returns true if the default template should be used.
+func (t PromptTemplate) IsZero() bool {
+	return t.File == "" && t.Text == ""
+}
+
+// CampaignPromptData that all prompt templates can use.
+type CampaignPromptData struct {
+	// Name of the campaign.
+	Name string
+	// Players by their ingame name including the characters of this session, sorted.
+	Players []string
+	// Actors by name.
+	Actors []string
+	// Scene that the session currently plays in. Can be empty.
+	Scene string
+	// Glossary of words of the campaign like places, items or factions. Can be empty.
+	Glossary []GlossaryTerm
+}
+
+// ActorPromptData is given to the NPC system prompt template. The fields of the actor like .Name or .Script can be used directly.
+type ActorPromptData struct {
+	*Actor
+	Campaign CampaignPromptData
+}
+
+// UserPromptData is given to the NPC user prompt template. The fields of the prompt context like .CurrentTranscript can be used directly.
+type UserPromptData struct {
+	PromptContext
+	Campaign CampaignPromptData
+}
+
+// prompts are the parsed templates that a campaign uses.
+type prompts struct {
+	npcSystem     *template.Template
+	npcUser       *template.Template
+	sttInit       *template.Template
+	summarization *template.Template
+	recap         *template.Template
+	correction    *template.Template
+}
+
+var summarizationPromptTemplate *template.Template
+
+func init() {
+	var err error
+	summarizationPromptTemplate, err = template.New("summarization").Parse(summarizationPrompt)
+	if err != nil {
+		panic(fmt.Errorf("could not parse summarization prompt template: %w", err))
+	}
+	if err := checkSummarizationTemplate(summarizationPromptTemplate); err != nil {
+		panic(fmt.Errorf("summarization prompt template can not be executed: %w", err))
+	}
+}
+
+// defaultPrompts that are embedded in the package.
+func defaultPrompts() *prompts {
+	return &prompts{
+		npcSystem:     npcSystemPromptTemplate,
+		npcUser:       npcUserPromptTemplate,
+		sttInit:       sttPromptTemplate,
+		summarization: summarizationPromptTemplate,
+		recap:         recapPromptTemplate,
+		correction:    correctionPromptTemplate,
+	}
+}
+
+// parse the overrides and check them with sample data. Missing overrides use the defaults.
+func (p Prompts) parse() (*prompts, error) {
+	parsed := defaultPrompts()
+	var err error
+	for _, override := range []struct {
+		name     string
+		template PromptTemplate
+		target   **template.Template
+		check    func(*template.Template) error
+	}{
+		{"npcSystem", p.NPCSystem, &parsed.npcSystem, checkNPCSystemTemplate},
+		{"npcUser", p.NPCUser, &parsed.npcUser, checkNPCUserTemplate},
+		{"sttInit", p.STTInit, &parsed.sttInit, checkSTTTemplate},
+		{"summarization", p.Summarization, &parsed.summarization, checkSummarizationTemplate},
+		{"recap", p.Recap, &parsed.recap, checkNPCSystemTemplate},
+		{"correction", p.Correction, &parsed.correction, checkSummarizationTemplate},
+	} {
+		if override.template.IsZero() {
+			continue
+		}
+		tpl, parseErr := template.New(override.name).Parse(override.template.Text)
+		if parseErr != nil {
+			err = errors.Join(err, fmt.Errorf("could not parse %s prompt template: %w", override.name, parseErr))
+			continue
+		}
+		if checkErr := override.check(tpl); checkErr != nil {
+			err = errors.Join(err, fmt.Errorf("%s prompt template can not be executed: %w", override.name, checkErr))
+			continue
+		}
+		*override.target = tpl
+	}
+	return parsed, err
+}
+
+var samplePromptData = CampaignPromptData{
+	Name:     "Test Me",
+	Players:  []string{"Me", "You"},
+	Actors:   []string{"Foo", "Bar"},
+	Scene:    "A tavern at night.",
+	Glossary: []GlossaryTerm{{Term: "Sun Tower"}, {Term: "Xyl'thar", Hints: []string{"Zil-tar"}}},
+}
+
+func checkNPCSystemTemplate(tpl *template.Template) error {
+	return tpl.Execute(io.Discard, ActorPromptData{
+		Actor: &Actor{
+			Name:    "Test Me",
+			Aliases: []string{"foo", "bar"},
+			Script: `A script
+		with some lines`,
+		},
+		Campaign: samplePromptData,
+	})
+}
+
+func checkNPCUserTemplate(tpl *template.Template) error {
+	return tpl.Execute(io.Discard, UserPromptData{
+		PromptContext: PromptContext{
+			Lore:           []string{"The Sun Tower is the tallest building in town."},
+			OldTranscripts: []string{"Test: Hello, World!\nGameMaster: Be quiet...", "Foo: Bar!"},
+			CurrentTranscript: `User 1: Hello
+		User 2: World!`,
+			Direction: "Tell them about the Sun Tower.",
+		},
+		Campaign: samplePromptData,
+	})
+}
+
+func checkSTTTemplate(tpl *template.Template) error {
+	return tpl.Execute(io.Discard, Campaign{
+		Name: "Test Me",
+		Players: map[string]string{
+			"test": "Me",
+		},
+		Actors: []*Actor{
+			{
+				Name: "Foo",
+			},
+			{
+				Name: "Bar",
+			},
+		},
+		Scene:    samplePromptData.Scene,
+		Glossary: samplePromptData.Glossary,
+	})
+}
+
+func checkSummarizationTemplate(tpl *template.Template) error {
+	return tpl.Execute(io.Discard, samplePromptData)
+}
+
+// promptData of the campaign for the prompt templates. Must be called while holding castMu or before the campaign is used.
+func (c *Campaign) promptData() CampaignPromptData {
+	data := CampaignPromptData{
+		Name:     c.Name,
+		Players:  make([]string, 0, len(c.Players)),
+		Actors:   make([]string, 0, len(c.Actors)),
+		Scene:    c.Scene,
+		Glossary: c.Glossary,
+	}
+	for _, player := range c.Players {
+		data.Players = append(data.Players, player)
+	}
+	for _, character := range c.Characters {
+		data.Players = append(data.Players, character)
+	}
+	slices.Sort(data.Players)
+	data.Players = slices.Compact(data.Players)
+	for _, actor := range c.Actors {
+		data.Actors = append(data.Actors, actor.Name)
+	}
+	return data
+}
+
+// applyPrompts of the campaign to all of its actors. Must be called while holding castMu or before the campaign is used.
+func (c *Campaign) applyPrompts() error {
+	data := c.promptData()
+	var err error
+	for _, actor := range c.Actors {
+		if actorErr := actor.usePrompts(c.prompts, data); actorErr != nil {
+			err = errors.Join(err, fmt.Errorf("system prompt of actor %q can not be created: %w", actor.Name, actorErr))
+		}
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package pnp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+	"gopkg.in/yaml.v3"
+)
+
+const promptsCampaignYaml = `name: Horror
+players:
+  "1234": Tharkhan
+  "5678": Lyra
+scene: Ein verlassenes Kloster im Nebel.
+prompts:
+  npcSystem: |-
+    Du bist {{ .Name }} in {{ .Campaign.Name }}. Spieler: {{ range .Campaign.Players }}{{ . }} {{ end }}
+    Szene: {{ .Campaign.Scene }}
+    {{ .Script }}
+  npcUser: "{{ .Campaign.Scene }}\n{{ .CurrentTranscript }}"
+  summarization:
+    file: %s
+actors:
+  - name: Brom
+    voice: onyx
+    script: Du flüsterst nur.
+`
+
+func promptsCampaign(t *testing.T, answer string, received *openai.ChatCompletionRequest) *Campaign {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "summary.tpl")
+	if err := os.WriteFile(path, []byte("Fasse die Sitzung von {{ .Name }} gruselig zusammen."), 0o644); err != nil {
+		t.Fatalf("could not write summary template: %v", err)
+	}
+	return campaignFromYaml(t, fmt.Sprintf(promptsCampaignYaml, path), answer, received)
+}
+
+func TestPromptOverrides(t *testing.T) {
+	var received openai.ChatCompletionRequest
+	c := promptsCampaign(t, "Psst.", &received)
+
+	if _, err := c.Actors[0].Act(context.Background(), c.model("Brom"), PromptContext{CurrentTranscript: "Tharkhan: Hallo?"}, nil); err != nil {
+		t.Fatalf("actor could not respond: %v", err)
+	}
+	expectedSystem := "Du bist Brom in Horror. Spieler: Lyra Tharkhan \nSzene: Ein verlassenes Kloster im Nebel.\nDu flüsterst nur."
+	if system := received.Messages[0].Content; system != expectedSystem {
+		t.Errorf("expected system prompt:\n%s\n\nbut got:\n%s", expectedSystem, system)
+	}
+	if user := received.Messages[1].Content; user != "Ein verlassenes Kloster im Nebel.\nTharkhan: Hallo?" {
+		t.Errorf("unexpected user prompt:\n%s", user)
+	}
+
+	c.SetChatModel(newChatStandIn(t, "{}", &received))
+	if _, err := c.Summary(); err != nil {
+		t.Fatalf("could not summarize: %v", err)
+	}
+	if system := received.Messages[0].Content; !strings.HasPrefix(system, "Fasse die Sitzung von Horror gruselig zusammen.\n\n") {
+		t.Errorf("expected summarization prompt from file but got:\n%s", system)
+	}
+}
+
+func TestPromptOverridesAreJournaledByFile(t *testing.T) {
+	c := promptsCampaign(t, "", nil)
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		t.Fatalf("could not marshal campaign: %v", err)
+	}
+	if !strings.Contains(string(data), "file: "+c.Prompts.Summarization.File) {
+		t.Errorf("expected the summarization prompt to be stored by its file:\n%s", data)
+	}
+	resumed, err := CampaignFromYaml(data)
+	if err != nil {
+		t.Fatalf("could not restore campaign: %v", err)
+	}
+	if resumed.Actors[0].systemPrompt != c.Actors[0].systemPrompt {
+		t.Errorf("expected system prompt:\n%s\n\nbut got:\n%s", c.Actors[0].systemPrompt, resumed.Actors[0].systemPrompt)
+	}
+}
+
+func TestInvalidPromptOverrides(t *testing.T) {
+	tests := map[string]string{
+		"syntax":       "npcSystem: \"{{ .Name \"",
+		"unknown data": "npcUser: \"{{ .Unknown }}\"",
+		"missing file": "sttInit:\n    file: does-not-exist.tpl",
+	}
+	for name, prompts := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := CampaignFromYaml([]byte("name: Test\nprompts:\n  " + prompts + "\nactors: []\n"))
+			if err == nil {
+				t.Error("expected the prompt override to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package pnp
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+)
+
+// CurrentActors of the campaign. The slice is a copy and stays the same even if the actors are reloaded meanwhile.
+func (c *Campaign) CurrentActors() []*Actor {
+	c.castMu.RLock()
+	defer c.castMu.RUnlock()
+	return slices.Clone(c.Actors)
+}
+
+// Reload the actors, narrator, players, scene, glossary and prompts from the campaign YAML data without interrupting the running session.
+// The data must be a valid campaign of the same name without any errors reported by Lint, otherwise nothing is changed
+// and the errors are returned.
+// Everything else like the transcript, the state and the turn policy is kept. The STT prompt only changes with the next session. Responses that are already running finish
+// with the actor as it was before the reload.
+func (c *Campaign) Reload(data []byte) error {
+	if err := lintError(Lint(data, "")); err != nil {
+		return err
+	}
+	next, err := CampaignFromYaml(data)
+	if err != nil {
+		return err
+	}
+	next.stop()
+	if next.Name != c.Name {
+		return fmt.Errorf("expected campaign %q but got %q", c.Name, next.Name)
+	}
+
+	c.castMu.Lock()
+	defer c.castMu.Unlock()
+	c.Actors = next.Actors
+	c.Narrator = next.Narrator
+	c.Players = next.Players
+	c.Scene = next.Scene
+	c.Glossary = next.Glossary
+	c.Prompts = next.Prompts
+	c.prompts = next.prompts
+	if len(c.Characters) > 0 {
+		// The new actors are not used yet, so they can still be changed
+		if err := c.applyPrompts(); err != nil {
+			slog.Warn("could not apply characters of this session to reloaded actors", "campaign", c.Name, "error", err)
+		}
+	}
+	slog.Info("reloaded campaign", "campaign", c.Name, "actors", len(c.Actors), "players", len(c.Players))
+	return nil
+}
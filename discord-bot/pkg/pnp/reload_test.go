@@ -0,0 +1,87 @@
+package pnp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const reloadedCampaignYaml = `players:
+  "1234": Tharkhan
+  "5678": Lyra
+actors:
+  - name: Petra Gabriel
+    aliases:
+      - Füchsin
+    voice: nova
+    script: Du bist Petra, eine misstrauische Händlerin.
+`
+
+func TestReloadKeepsTranscript(t *testing.T) {
+	c := dialogueCampaign(t, 1, 10)
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie, was meinst du?"})
+	collectResponses(c, nil)
+	transcript := c.CurrentTranscript()
+
+	if err := c.Reload([]byte(testCampaignYamlWith(t, reloadedCampaignYaml))); err != nil {
+		t.Fatalf("could not reload campaign: %v", err)
+	}
+	if c.CurrentTranscript() != transcript {
+		t.Errorf("expected transcript to be kept but got:\n%s", c.CurrentTranscript())
+	}
+	actors := c.CurrentActors()
+	if len(actors) != 1 || !actors[0].IsAdressed("Na, Füchsin?") || actors[0].IsAdressed("Na, Foxie?") {
+		t.Errorf("expected actors and their aliases to be reloaded but got %+v", actors)
+	}
+	if !strings.Contains(actors[0].systemPrompt, "misstrauische Händlerin") {
+		t.Errorf("expected system prompt to be updated but got:\n%s", actors[0].systemPrompt)
+	}
+	if name, ok := c.PlayerName("5678"); !ok || name != "Lyra" {
+		t.Errorf("expected players to be reloaded but got %q", name)
+	}
+
+	c.HandleText(TranscriptEntry{SpeakerID: "5678", Name: "Lyra", Source: SourceSTT, Text: "Brom, bist du noch da?"})
+	if names := collectResponses(c, nil); len(names) != 0 {
+		t.Errorf("expected removed actor to stay silent but got %q", names)
+	}
+}
+
+func TestReloadRejectsInvalidCampaign(t *testing.T) {
+	tests := map[string]string{
+		"invalid yaml":   "name: [Test",
+		"other campaign": "name: Other\nactors: []\n",
+		"unknown tool":   "name: Test\nactors:\n  - name: Brom\n    tools:\n      - cast_fireball\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := dialogueCampaign(t, 1, 10)
+			if err := c.Reload([]byte(data)); err == nil {
+				t.Fatal("expected reload to fail")
+			}
+			if names := fmt.Sprint(actorNames(c.CurrentActors())); names != "[Petra Gabriel Brom]" {
+				t.Errorf("expected actors to be kept after a failed reload but got %s", names)
+			}
+		})
+	}
+}
+
+func TestReloadWhileResponding(t *testing.T) {
+	c := dialogueCampaign(t, 3, 10)
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie, was meinst du?"})
+	for range 5 {
+		if err := c.Reload([]byte(testCampaignYamlWith(t, fmt.Sprintf(dialogueCampaignYaml, 3, 10)))); err != nil {
+			t.Fatalf("could not reload campaign: %v", err)
+		}
+	}
+	if names := collectResponses(c, nil); len(names) == 0 {
+		t.Error("expected actors to keep responding while being reloaded")
+	}
+}
+
+func actorNames(actors []*Actor) []string {
+	names := make([]string, len(actors))
+	for i, actor := range actors {
+		names[i] = actor.Name
+	}
+	return names
+}
@@ -0,0 +1,124 @@
+package pnp
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+)
+
+//go:embed rolling_summary_prompt.txt
+var rollingSummaryPrompt string
+
+const (
+	defaultRollingThreshold = 150
+	defaultRollingKeep      = 50
+)
+
+// RollingSummaryConfig configures how the live transcript is condensed into a story so far while the session is running.
+type RollingSummaryConfig struct {
+	// Disabled turns off the rolling summary so actors will always see the whole current transcript.
+	Disabled bool `yaml:"disabled"`
+	// Threshold of verbatim transcript lines that triggers a summary. Defaults to 150.
+	Threshold int `yaml:"threshold"`
+	// Keep this many of the latest lines verbatim after summarizing. Defaults to 50.
+	Keep int `yaml:"keep"`
+	// Model to use instead of the default model of the campaign.
+	Model string `yaml:"model"`
+}
+
+// Validate that the thresholds are usable.
+func (cfg RollingSummaryConfig) Validate() error {
+	var err error
+	if cfg.Threshold < 0 {
+		err = errors.Join(err, fmt.Errorf("rolling summary threshold must not be negative but is %d", cfg.Threshold))
+	}
+	if cfg.Keep < 0 {
+		err = errors.Join(err, fmt.Errorf("rolling summary keep must not be negative but is %d", cfg.Keep))
+	}
+	if cfg.keep() >= cfg.threshold() {
+		err = errors.Join(err, fmt.Errorf("rolling summary must keep less lines than its threshold of %d but keeps %d", cfg.threshold(), cfg.keep()))
+	}
+	return err
+}
+
+func (cfg RollingSummaryConfig) threshold() int {
+	if cfg.Threshold == 0 {
+		return defaultRollingThreshold
+	}
+	return cfg.Threshold
+}
+
+func (cfg RollingSummaryConfig) keep() int {
+	if cfg.Keep == 0 {
+		return defaultRollingKeep
+	}
+	return cfg.Keep
+}
+
+// currentPromptContext with the story so far and all transcript lines that were not summarized yet.
+func (c *Campaign) currentPromptContext() PromptContext {
+	c.transcriptMu.Lock()
+	defer c.transcriptMu.Unlock()
+	entries := c.Transcript.Entries()
+	return PromptContext{
+		StorySoFar:        c.StorySoFar,
+		CurrentTranscript: c.Classifier.render(entries[min(c.SummarizedLines, len(entries)):], c.Classifier.Prompts),
+	}
+}
+
+// summarizeIfNeeded starts a background summary of older transcript lines once the threshold has been crossed.
+// After a failed summary it waits until another threshold's worth of lines was added before trying again.
+// Must be called while holding transcriptMu.
+func (c *Campaign) summarizeIfNeeded() {
+	if c.RollingSummary.Disabled || c.summarizing || c.chatModel == nil {
+		return
+	}
+	total := c.Transcript.Len()
+	if total-c.SummarizedLines <= c.RollingSummary.threshold() || total < c.summaryRetryAt {
+		return
+	}
+	if !c.addResponder() {
+		return // Campaign is closing, the journal keeps the lines for the next session
+	}
+	c.summarizing = true
+	entries := c.Transcript.Entries()[c.SummarizedLines : total-c.RollingSummary.keep()]
+	storySoFar := c.StorySoFar
+	go func() {
+		defer c.responders.Done()
+		story, err := c.rollingSummary(storySoFar, entries)
+
+		c.transcriptMu.Lock()
+		defer c.transcriptMu.Unlock()
+		c.summarizing = false
+		if err != nil {
+			c.summaryRetryAt = c.Transcript.Len() + c.RollingSummary.threshold()
+			slog.Warn("could not update story so far", "campaign", c.Name, "retryAtLine", c.summaryRetryAt, "error", err)
+			return
+		}
+		c.StorySoFar = story
+		c.SummarizedLines += len(entries)
+	}()
+}
+
+func (c *Campaign) rollingSummary(storySoFar string, entries []TranscriptEntry) (string, error) {
+	resp, err := c.model(UsageSummary).Chat(c.ctx, llm.Request{
+		Model: c.RollingSummary.Model,
+		Messages: []llm.Message{
+			{
+				Role:    llm.RoleSystem,
+				Content: rollingSummaryPrompt,
+			},
+			{
+				Role:    llm.RoleUser,
+				Content: "- STORY SO FAR -\n" + storySoFar + "\n\n- NEW TRANSCRIPT -\n" + c.Classifier.render(entries, c.Classifier.Summaries),
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
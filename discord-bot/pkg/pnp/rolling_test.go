@@ -0,0 +1,140 @@
+package pnp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+)
+
+func TestRollingSummary(t *testing.T) {
+	c, err := CampaignFromYaml([]byte(testCampaignYaml + `rollingSummary:
+  threshold: 4
+  keep: 2
+`))
+	if err != nil {
+		t.Fatalf("could not read test campaign: %v", err)
+	}
+	c.SetChatModel(newChatStandIn(t, "1. Die Helden kommen in Interlumen an.", nil))
+
+	for i := range 5 {
+		c.HandleText(TranscriptEntry{Name: "Tharkhan", Text: fmt.Sprintf("Zeile %d", i)})
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		ctx := c.currentPromptContext()
+		if ctx.StorySoFar != "" {
+			if ctx.StorySoFar != "1. Die Helden kommen in Interlumen an." {
+				t.Errorf("unexpected story so far %q", ctx.StorySoFar)
+			}
+			if ctx.CurrentTranscript != "Tharkhan: Zeile 3\nTharkhan: Zeile 4" {
+				t.Errorf("expected only the latest lines to stay verbatim but got:\n%s", ctx.CurrentTranscript)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("story so far was not created in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if c.Transcript.Len() != 5 {
+		t.Errorf("summarizing must not remove lines from the transcript but it has %d lines", c.Transcript.Len())
+	}
+}
+
+// unreachableModel fails every request. If block is set it fails only once the context of the request is done.
+type unreachableModel struct {
+	block bool
+	calls atomic.Int32
+	done  atomic.Int32
+}
+
+func (m *unreachableModel) Chat(ctx context.Context, _ llm.Request) (llm.Response, error) {
+	m.calls.Add(1)
+	defer m.done.Add(1)
+	if m.block {
+		<-ctx.Done()
+		return llm.Response{}, ctx.Err()
+	}
+	return llm.Response{}, errors.New("model is unreachable")
+}
+
+func (m *unreachableModel) ChatStream(ctx context.Context, req llm.Request) (llm.Stream, error) {
+	_, err := m.Chat(ctx, req)
+	return nil, err
+}
+
+func TestRollingSummaryRetriesAfterThreshold(t *testing.T) {
+	c := campaignFromYaml(t, "rollingSummary:\n  threshold: 4\n  keep: 2\n", "", nil)
+	model := &unreachableModel{}
+	c.SetChatModel(model)
+
+	for i := range 9 {
+		c.HandleText(TranscriptEntry{Name: "Tharkhan", Text: fmt.Sprintf("Zeile %d", i)})
+		c.responders.Wait()
+		expected := int32(0)
+		if i >= 4 {
+			expected = 1
+		}
+		if i >= 8 {
+			expected = 2
+		}
+		if calls := model.calls.Load(); calls != expected {
+			t.Fatalf("expected %d summary requests after %d lines but got %d", expected, i+1, calls)
+		}
+	}
+}
+
+func TestSuspendStopsRollingSummary(t *testing.T) {
+	c := campaignFromYaml(t, "rollingSummary:\n  threshold: 4\n  keep: 2\n", "", nil)
+	model := &unreachableModel{block: true}
+	c.SetChatModel(model)
+
+	for i := range 5 {
+		c.HandleText(TranscriptEntry{Name: "Tharkhan", Text: fmt.Sprintf("Zeile %d", i)})
+	}
+	if err := c.Suspend(); err != nil {
+		t.Fatalf("could not suspend campaign: %v", err)
+	}
+	if model.calls.Load() != 1 || model.done.Load() != 1 {
+		t.Errorf("expected the summary to be cancelled and waited for but %d of %d requests are done", model.done.Load(), model.calls.Load())
+	}
+}
+
+func TestRollingSummaryConfigValidate(t *testing.T) {
+	invalid := []RollingSummaryConfig{
+		{Threshold: -1},
+		{Keep: -1},
+		{Threshold: 10, Keep: 10},
+		{Threshold: 20},
+	}
+	for _, cfg := range invalid {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected config %+v to be invalid", cfg)
+		}
+	}
+	if err := (RollingSummaryConfig{}).Validate(); err != nil {
+		t.Errorf("expected default config to be valid but got: %v", err)
+	}
+}
+
+func TestNpcUserTemplateWithStorySoFar(t *testing.T) {
+	promptBuf := bytes.NewBuffer(make([]byte, 0))
+	err := npcUserPromptTemplate.Execute(promptBuf, PromptContext{
+		StorySoFar:        "1. Es war einmal...",
+		CurrentTranscript: "Amon: Ja.",
+	})
+	if err != nil {
+		t.Fatalf("got unexpected error while resolving user prompt template: %v", err)
+	}
+	if !strings.Contains(promptBuf.String(), "\n\n- STORY SO FAR -\n1. Es war einmal...\n\n- CURRENT TRANSCRIPT -\nAmon: Ja.") {
+		t.Errorf("story so far is missing in user prompt:\n%s", promptBuf.String())
+	}
+}
@@ -0,0 +1,78 @@
+package pnp
+
+import (
+	"strings"
+	"unicode"
+)
+
+// sentenceSplitter collects streamed text and splits it into complete sentences, so each of them can be spoken as soon as possible.
+type sentenceSplitter struct {
+	pending string
+}
+
+// Write the next piece of streamed text and return all sentences that are complete now.
+func (s *sentenceSplitter) Write(text string) []string {
+	s.pending += text
+	sentences := make([]string, 0)
+	for {
+		end := sentenceEnd(s.pending)
+		if end < 0 {
+			return sentences
+		}
+		if sentence := strings.TrimSpace(s.pending[:end]); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		s.pending = s.pending[end:]
+	}
+}
+
+// Flush the remaining text that didn't end with a sentence boundary.
+func (s *sentenceSplitter) Flush() string {
+	rest := strings.TrimSpace(s.pending)
+	s.pending = ""
+	return rest
+}
+
+// sentenceEnd returns the index after the first complete sentence in text or -1 if there is none yet.
+// A sentence ends with a line break or with terminal punctuation (and closing quotes) followed by whitespace.
+// Enumerations like "1. " don't end a sentence.
+func sentenceEnd(text string) int {
+	runes := []rune(text)
+	offset := 0
+	for i, r := range runes {
+		offset += len(string(r))
+		if r == '\n' {
+			return offset
+		}
+		if !strings.ContainsRune(".!?…", r) {
+			continue
+		}
+		end := i + 1
+		endOffset := offset
+		for end < len(runes) && strings.ContainsRune(".!?…\"'»“”)", runes[end]) {
+			endOffset += len(string(runes[end]))
+			end++
+		}
+		if end >= len(runes) {
+			// Need to see what follows to decide
+			return -1
+		}
+		if !unicode.IsSpace(runes[end]) || isEnumeration(runes[:i]) {
+			continue
+		}
+		return endOffset
+	}
+	return -1
+}
+
+// isEnumeration returns true if the text ends with a word that only consists of digits like in "1." or "2152.".
+func isEnumeration(text []rune) bool {
+	digits := 0
+	for i := len(text) - 1; i >= 0 && !unicode.IsSpace(text[i]); i-- {
+		if !unicode.IsDigit(text[i]) {
+			return false
+		}
+		digits++
+	}
+	return digits > 0
+}
@@ -0,0 +1,34 @@
+package pnp
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSentenceSplitter(t *testing.T) {
+	tests := []struct {
+		deltas   []string
+		expected []string
+	}{
+		{[]string{"Hallo ", "Welt. ", "Wie geht's?"}, []string{"Hallo Welt.", "Wie geht's?"}},
+		{[]string{"Was?! Nein", "... ", "Doch!"}, []string{"Was?!", "Nein...", "Doch!"}},
+		{[]string{"Er sagte: \"Lauf!\" ", "Und ich lief."}, []string{"Er sagte: \"Lauf!\"", "Und ich lief."}},
+		{[]string{"Du brauchst:\n1. ", "ein Seil\n2. eine Fackel"}, []string{"Du brauchst:", "1. ein Seil", "2. eine Fackel"}},
+		{[]string{"Es kostet 3.5 Gold", "stücke."}, []string{"Es kostet 3.5 Goldstücke."}},
+		{[]string{"Kein Satzende"}, []string{"Kein Satzende"}},
+	}
+	for _, test := range tests {
+		var splitter sentenceSplitter
+		actual := make([]string, 0)
+		for _, delta := range test.deltas {
+			actual = append(actual, splitter.Write(delta)...)
+		}
+		if rest := splitter.Flush(); rest != "" {
+			actual = append(actual, rest)
+		}
+		if !slices.Equal(actual, test.expected) {
+			t.Errorf("expected %q to be split into %q but got %q", strings.Join(test.deltas, ""), test.expected, actual)
+		}
+	}
+}
@@ -1 +1 @@
-This is a pen and paper session with players {{ range $uid, $name := .Players }}{{ $name }},{{ end }} and NPCs {{ range .Actors }}{{ .Name }},{{ end }}.
\ No newline at end of file
+This is a pen and paper session with players {{ range $uid, $name := .Players }}{{ $name }},{{ end }} and NPCs {{ range .Actors }}{{ .Name }},{{ end }}.{{ if .Glossary }} Words of the campaign: {{ range .Glossary }}{{ .Term }},{{ end }}.{{ end }}
\ No newline at end of file
@@ -0,0 +1,232 @@
+package pnp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+)
+
+// Names of the built-in tools that actors can be allowed to use.
+const (
+	ToolRollDice          = "roll_dice"
+	ToolSearchTranscripts = "search_transcripts"
+	ToolGetState          = "get_state"
+	ToolSetState          = "set_state"
+	ToolGiveItem          = "give_item"
+)
+
+// maxToolRounds that an actor may call tools in before it has to answer.
+const maxToolRounds = 4
+
+var builtinTools = map[string]llm.Tool{
+	ToolRollDice: {
+		Name:        ToolRollDice,
+		Description: "Roll dice for a check or a random outcome. The result is visible to everyone.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"notation":{"type":"string","description":"Dice notation like 1d20+3, 4d6kh3, d20 adv or 4dF"}},"required":["notation"]}`),
+	},
+	ToolSearchTranscripts: {
+		Name:        ToolSearchTranscripts,
+		Description: "Search the transcripts of past sessions of this campaign to remember what happened.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"query":{"type":"string","description":"What to search for"}},"required":["query"]}`),
+	},
+	ToolGetState: {
+		Name:        ToolGetState,
+		Description: "Read the game state of the campaign like prices, stock or quest progress.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"key":{"type":"string","description":"Key to read. Leave empty to list all entries."}}}`),
+	},
+	ToolSetState: {
+		Name:        ToolSetState,
+		Description: "Write an entry of the game state of the campaign like a changed stock or a finished sale.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"key":{"type":"string"},"value":{"type":"string","description":"New value. Leave empty to remove the entry."}},"required":["key"]}`),
+	},
+	ToolGiveItem: {
+		Name:        ToolGiveItem,
+		Description: "Hand an item to a player so it is added to their inventory.",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"player":{"type":"string","description":"Name of the player character"},"item":{"type":"string"},"quantity":{"type":"integer","minimum":1}},"required":["player","item"]}`),
+	},
+}
+
+// validateTools that an actor may use.
+func validateTools(names []string) error {
+	var err error
+	for _, name := range names {
+		if _, ok := builtinTools[name]; !ok {
+			err = errors.Join(err, fmt.Errorf("unknown tool %q", name))
+		}
+	}
+	return err
+}
+
+// Toolbox executes the tool calls of actors.
+type Toolbox interface {
+	// Tools that the actor may call.
+	Tools(actor *Actor) []llm.Tool
+	// Call the tool and return the result that the model will see.
+	Call(ctx context.Context, actor *Actor, call llm.ToolCall) (string, error)
+}
+
+// campaignToolbox provides the built-in tools operating on the campaign and logs every call in its transcript.
+type campaignToolbox struct {
+	c *Campaign
+}
+
+// Tools implements Toolbox.
+func (t campaignToolbox) Tools(actor *Actor) []llm.Tool {
+	tools := make([]llm.Tool, 0, len(actor.Tools))
+	for _, name := range actor.Tools {
+		tools = append(tools, builtinTools[name])
+	}
+	return tools
+}
+
+// Call implements Toolbox.
+func (t campaignToolbox) Call(ctx context.Context, actor *Actor, call llm.ToolCall) (string, error) {
+	start := time.Now()
+	result, err := t.call(ctx, actor, call)
+	text := result
+	if err != nil {
+		text = "error: " + err.Error()
+	}
+	slog.Info("actor called tool", "campaign", t.c.Name, "name", actor.Name, "tool", call.Name, "arguments", call.Arguments, "result", text)
+	t.c.appendEntry(TranscriptEntry{
+		SpeakerID: actor.Name,
+		Name:      actor.Name,
+		Start:     start,
+		End:       time.Now(),
+		Source:    SourceTool,
+		Text:      fmt.Sprintf("%s %s → %s", call.Name, call.Arguments, text),
+	})
+	return result, err
+}
+
+func (t campaignToolbox) call(_ context.Context, actor *Actor, call llm.ToolCall) (string, error) {
+	if !slices.Contains(actor.Tools, call.Name) {
+		return "", fmt.Errorf("tool %q is not available", call.Name)
+	}
+	var args struct {
+		Notation string `json:"notation"`
+		Query    string `json:"query"`
+		Key      string `json:"key"`
+		Value    string `json:"value"`
+		Player   string `json:"player"`
+		Item     string `json:"item"`
+		Quantity int    `json:"quantity"`
+	}
+	if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	switch call.Name {
+	case ToolRollDice:
+		return t.c.rollDice(args.Notation)
+	case ToolSearchTranscripts:
+		return t.c.searchTranscripts(args.Query)
+	case ToolGetState:
+		return t.c.getState(args.Key), nil
+	case ToolSetState:
+		return t.c.setState(args.Key, args.Value)
+	case ToolGiveItem:
+		return t.c.giveItem(args.Player, args.Item, max(args.Quantity, 1))
+	default:
+		return "", fmt.Errorf("tool %q is not implemented", call.Name)
+	}
+}
+
+func (c *Campaign) rollDice(notation string) (string, error) {
+	res, err := c.Roll(notation)
+	if err != nil {
+		return "", err
+	}
+	return res.String(), nil
+}
+
+func (c *Campaign) searchTranscripts(query string) (string, error) {
+	if c.dbClient == nil {
+		return "", errors.New("no past transcripts available")
+	}
+	c.recordEmbedding(query)
+	results, err := c.dbClient.SearchTranscripts(c.Name, query)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return "nothing found", nil
+	}
+	return strings.Join(results, "\n---\n"), nil
+}
+
+func (c *Campaign) getState(key string) string {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if key != "" {
+		value, ok := c.State[key]
+		if !ok {
+			return key + " is not set"
+		}
+		return key + " = " + value
+	}
+	if len(c.State) == 0 {
+		return "the game state is empty"
+	}
+	lines := make([]string, 0, len(c.State))
+	for k, v := range c.State {
+		lines = append(lines, k+" = "+v)
+	}
+	slices.Sort(lines)
+	return strings.Join(lines, "\n")
+}
+
+func (c *Campaign) setState(key, value string) (string, error) {
+	if key == "" {
+		return "", errors.New("key must not be empty")
+	}
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if value == "" {
+		delete(c.State, key)
+		return key + " removed", nil
+	}
+	if c.State == nil {
+		c.State = make(map[string]string)
+	}
+	c.State[key] = value
+	return key + " = " + value, nil
+}
+
+func (c *Campaign) giveItem(player, item string, quantity int) (string, error) {
+	if item == "" {
+		return "", errors.New("item must not be empty")
+	}
+	name, ok := c.playerByName(player)
+	if !ok {
+		return "", fmt.Errorf("there is no player called %q", player)
+	}
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if c.Inventory == nil {
+		c.Inventory = make(map[string]map[string]int)
+	}
+	if c.Inventory[name] == nil {
+		c.Inventory[name] = make(map[string]int)
+	}
+	c.Inventory[name][item] += quantity
+	return fmt.Sprintf("%s now has %d %s", name, c.Inventory[name][item], item), nil
+}
+
+// playerByName returns the ingame name of the player that matches name regardless of its case.
+func (c *Campaign) playerByName(name string) (string, bool) {
+	c.castMu.RLock()
+	defer c.castMu.RUnlock()
+	for _, playerName := range c.Players {
+		if strings.EqualFold(playerName, strings.TrimSpace(name)) {
+			return playerName, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,219 @@
+package pnp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/dice"
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+	"github.com/sashabaranov/go-openai"
+)
+
+const shopCampaignYaml = `name: Shop
+players:
+  "1234": Tharkhan
+state:
+  "price:sword": 15 gold
+  "stock:sword": "2"
+actors:
+  - name: Brom
+    voice: onyx
+    tools:
+      - get_state
+      - set_state
+      - give_item
+    script: Du bist Brom, ein Waffenhändler.
+`
+
+// newToolStandIn streams the tool calls of each round one after another as answers to the streamed requests. Once all rounds
+// were answered the answer is streamed. All received requests are stored in received.
+func newToolStandIn(t *testing.T, rounds [][]llm.ToolCall, answer string, received *[]openai.ChatCompletionRequest) llm.ChatModel {
+	t.Helper()
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("could not decode chat completion request: %v", err)
+		}
+		mu.Lock()
+		*received = append(*received, req)
+		round := len(*received) - 1
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		var deltas []openai.ChatCompletionStreamChoiceDelta
+		if round < len(rounds) {
+			for i, call := range rounds[round] {
+				deltas = append(deltas, openai.ChatCompletionStreamChoiceDelta{
+					ToolCalls: []openai.ToolCall{{
+						Index:    &i,
+						ID:       call.ID,
+						Type:     openai.ToolTypeFunction,
+						Function: openai.FunctionCall{Name: call.Name, Arguments: call.Arguments},
+					}},
+				})
+			}
+		} else {
+			for _, word := range strings.SplitAfter(answer, " ") {
+				deltas = append(deltas, openai.ChatCompletionStreamChoiceDelta{Content: word})
+			}
+		}
+		for _, delta := range deltas {
+			chunk, _ := json.Marshal(openai.ChatCompletionStreamResponse{
+				Choices: []openai.ChatCompletionStreamChoice{{Delta: delta}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(srv.Close)
+	model, err := llm.NewOpenAICompatible(srv.URL+"/v1", "", "test-model")
+	if err != nil {
+		t.Fatalf("could not create chat model: %v", err)
+	}
+	return model
+}
+
+func TestShopkeeperSellsItem(t *testing.T) {
+	c, err := CampaignFromYaml([]byte(shopCampaignYaml))
+	if err != nil {
+		t.Fatalf("could not read shop campaign: %v", err)
+	}
+	rounds := [][]llm.ToolCall{
+		{{ID: "call_1", Name: ToolGetState, Arguments: `{"key":"price:sword"}`}},
+		{
+			{ID: "call_2", Name: ToolGiveItem, Arguments: `{"player":"tharkhan","item":"sword"}`},
+			{ID: "call_3", Name: ToolSetState, Arguments: `{"key":"stock:sword","value":"1"}`},
+		},
+	}
+	var received []openai.ChatCompletionRequest
+	model := newToolStandIn(t, rounds, "Das macht 15 Gold. Viel Spaß damit!", &received)
+
+	var sentences []string
+	answer, err := c.Actors[0].ActStream(context.Background(), model, PromptContext{CurrentTranscript: "Tharkhan: Was kostet das Schwert?"}, campaignToolbox{c}, func(sentence string) {
+		sentences = append(sentences, sentence)
+	})
+	if err != nil {
+		t.Fatalf("actor could not respond: %v", err)
+	}
+	if answer != "Das macht 15 Gold. Viel Spaß damit!" || len(sentences) != 2 {
+		t.Errorf("unexpected answer %q with sentences %q", answer, sentences)
+	}
+
+	if len(received) != 3 {
+		t.Fatalf("expected 3 chat requests but got %d", len(received))
+	}
+	if len(received[0].Tools) != 3 {
+		t.Errorf("expected the actor to be offered 3 tools but got %d", len(received[0].Tools))
+	}
+	priceResult := received[1].Messages[len(received[1].Messages)-1]
+	if priceResult.Role != openai.ChatMessageRoleTool || priceResult.ToolCallID != "call_1" || priceResult.Content != "price:sword = 15 gold" {
+		t.Errorf("unexpected tool result message: %+v", priceResult)
+	}
+	if n := len(received[2].Messages); n != 7 {
+		t.Errorf("expected 7 messages in the last request but got %d", n)
+	}
+
+	if c.Inventory["Tharkhan"]["sword"] != 1 {
+		t.Errorf("expected Tharkhan to have a sword but inventory is %v", c.Inventory)
+	}
+	if c.State["stock:sword"] != "1" {
+		t.Errorf("expected stock to be recorded but state is %v", c.State)
+	}
+	entries := c.Transcript.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("expected every tool call to be logged but transcript is:\n%s", c.CurrentTranscript())
+	}
+	if entries[1].Source != SourceTool || entries[1].String() != `Brom (tool): give_item {"player":"tharkhan","item":"sword"} → Tharkhan now has 1 sword` {
+		t.Errorf("unexpected tool entry %q", entries[1].String())
+	}
+}
+
+func TestToolLoopIsBounded(t *testing.T) {
+	c, err := CampaignFromYaml([]byte(shopCampaignYaml))
+	if err != nil {
+		t.Fatalf("could not read shop campaign: %v", err)
+	}
+	rounds := make([][]llm.ToolCall, maxToolRounds+1)
+	for i := range rounds {
+		rounds[i] = []llm.ToolCall{{ID: fmt.Sprintf("call_%d", i), Name: ToolGetState, Arguments: `{}`}}
+	}
+	var received []openai.ChatCompletionRequest
+	model := newToolStandIn(t, rounds, "Hm.", &received)
+
+	if _, err := c.Actors[0].ActStream(context.Background(), model, PromptContext{}, campaignToolbox{c}, func(string) {}); err != nil {
+		t.Fatalf("actor could not respond: %v", err)
+	}
+	if len(received) != maxToolRounds+1 {
+		t.Fatalf("expected %d chat requests but got %d", maxToolRounds+1, len(received))
+	}
+	if len(received[maxToolRounds].Tools) != 0 {
+		t.Error("expected the last request to offer no tools")
+	}
+	if n := c.Transcript.Len(); n != maxToolRounds {
+		t.Errorf("expected %d tool calls but got %d", maxToolRounds, n)
+	}
+}
+
+func TestToolErrors(t *testing.T) {
+	c, err := CampaignFromYaml([]byte(shopCampaignYaml))
+	if err != nil {
+		t.Fatalf("could not read shop campaign: %v", err)
+	}
+	toolbox := campaignToolbox{c}
+	brom := c.Actors[0]
+
+	tests := []llm.ToolCall{
+		{Name: ToolGiveItem, Arguments: `{"player":"Nobody","item":"sword"}`},
+		{Name: ToolRollDice, Arguments: `{"notation":"1d20"}`},
+		{Name: ToolSetState, Arguments: `{"value":"1"}`},
+		{Name: ToolGetState, Arguments: `not json`},
+	}
+	for _, call := range tests {
+		if result, err := toolbox.Call(context.Background(), brom, call); err == nil {
+			t.Errorf("expected %s %s to fail but got %q", call.Name, call.Arguments, result)
+		}
+	}
+	if len(c.Inventory) != 0 {
+		t.Errorf("failed calls must not change the inventory: %v", c.Inventory)
+	}
+	if n := c.Transcript.Len(); n != len(tests) {
+		t.Errorf("expected failed calls to be logged too but got %d entries", n)
+	}
+}
+
+func TestRollDiceToolUsesCampaignRoller(t *testing.T) {
+	c, err := CampaignFromYaml([]byte(shopCampaignYaml))
+	if err != nil {
+		t.Fatalf("could not read shop campaign: %v", err)
+	}
+	c.Actors[0].Tools = append(c.Actors[0].Tools, ToolRollDice)
+	c.SetDiceRoller(dice.NewRoller(5))
+
+	result, err := campaignToolbox{c}.Call(context.Background(), c.Actors[0], llm.ToolCall{Name: ToolRollDice, Arguments: `{"notation":"4d6kh3"}`})
+	if err != nil {
+		t.Fatalf("could not roll dice: %v", err)
+	}
+	expected, _ := dice.NewRoller(5).Roll("4d6kh3")
+	if result != expected.String() {
+		t.Errorf("expected the seeded roll %q but got %q", expected, result)
+	}
+}
+
+func TestActorWithUnknownTool(t *testing.T) {
+	_, err := CampaignFromYaml([]byte(`name: Test
+actors:
+  - name: Brom
+    tools:
+      - cast_fireball
+`))
+	if err == nil || !strings.Contains(err.Error(), "cast_fireball") {
+		t.Errorf("expected unknown tool to be rejected but got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package pnp
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source of a transcript line.
+type Source string
+
+const (
+	// SourceSTT for lines that were transcribed from a voice channel.
+	SourceSTT Source = "stt"
+	// SourceNPC for lines that an actor responded with.
+	SourceNPC Source = "npc"
+	// SourceChat for lines that were typed in a text channel.
+	SourceChat Source = "chat"
+	// SourceTool for tool calls that an actor made while responding.
+	SourceTool Source = "tool"
+	// SourceDice for dice rolls of players.
+	SourceDice Source = "dice"
+)
+
+// TranscriptEntry is a single line of a transcript.
+type TranscriptEntry struct {
+	// SpeakerID that uniquely identifies the speaker like a Discord user ID or the name of an actor.
+	SpeakerID string `yaml:"speakerID,omitempty"`
+	// Name of the speaker as it should be displayed.
+	Name string `yaml:"name"`
+	// Start of the line. Can be zero if unknown.
+	Start time.Time `yaml:"start,omitempty"`
+	// End of the line. Can be zero if unknown.
+	End time.Time `yaml:"end,omitempty"`
+	// Source that the line originated from. Can be empty if unknown.
+	Source Source `yaml:"source,omitempty"`
+	// Text that was said.
+	Text string `yaml:"text"`
+	// Interrupted is true if the speaker got cut off, so Text only contains what was said until then.
+	Interrupted bool `yaml:"interrupted,omitempty"`
+	// ChainStart is the line number, starting at 1, of the player line that started the exchange that this actor line is part of.
+	// 0 if the line is not an actor response.
+	ChainStart int `yaml:"chainStart,omitempty"`
+	// ChainDepth of the actor line within its exchange. 1 for direct responses to the player line, 2 for responses to those and so on.
+	ChainDepth int `yaml:"chainDepth,omitempty"`
+	// Tag that tells how the line belongs to the role-play. Empty if the line has not been classified.
+	Tag Tag `yaml:"tag,omitempty"`
+}
+
+// String renders the entry as "Name: Text". Interrupted entries end with an em dash and tool calls are rendered as "Name (tool): Text".
+func (e TranscriptEntry) String() string {
+	if e.Source == SourceTool {
+		return fmt.Sprintf("%s (tool): %s", e.Name, e.Text)
+	}
+	if e.Interrupted {
+		return fmt.Sprintf("%s: %s—", e.Name, e.Text)
+	}
+	return fmt.Sprintf("%s: %s", e.Name, e.Text)
+}
+
+// Transcript of a session. It is safe for concurrent use.
+type Transcript struct {
+	mu      sync.Mutex
+	entries []TranscriptEntry
+}
+
+// NewTranscript containing the given entries.
+func NewTranscript(entries ...TranscriptEntry) *Transcript {
+	return &Transcript{
+		entries: entries,
+	}
+}
+
+// ParseTranscript from its plain text rendering where each line has the format "Name: Text".
+// Lines without a name will be added to the text of the previous entry.
+func ParseTranscript(text string) *Transcript {
+	t := NewTranscript()
+	for _, line := range strings.Split(text, "\n") {
+		name, segment, ok := strings.Cut(line, ": ")
+		switch {
+		case ok:
+			t.entries = append(t.entries, TranscriptEntry{
+				SpeakerID: name,
+				Name:      name,
+				Text:      segment,
+			})
+		case len(t.entries) > 0:
+			t.entries[len(t.entries)-1].Text += "\n" + line
+		case strings.TrimSpace(line) != "":
+			t.entries = append(t.entries, TranscriptEntry{
+				Text: line,
+			})
+		}
+	}
+	return t
+}
+
+// Append the entry and return its index within the transcript.
+func (t *Transcript) Append(entry TranscriptEntry) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = append(t.entries, entry)
+	return len(t.entries) - 1
+}
+
+// SetTag of the entry at index.
+func (t *Transcript) SetTag(index int, tag Tag) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries[index].Tag = tag
+}
+
+// Entries of the transcript. The returned slice is a copy.
+func (t *Transcript) Entries() []TranscriptEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entries := make([]TranscriptEntry, len(t.entries))
+	copy(entries, t.entries)
+	return entries
+}
+
+// Len is the number of entries.
+func (t *Transcript) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.entries)
+}
+
+// String renders the transcript as plain text with one "Name: Text" line per entry.
+func (t *Transcript) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return renderEntries(t.entries)
+}
+
+func renderEntries(entries []TranscriptEntry) string {
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = entry.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// UnmarshalYAML accepts either a list of entries or the plain text rendering of a transcript.
+func (t *Transcript) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var text string
+		if err := value.Decode(&text); err != nil {
+			return err
+		}
+		t.entries = ParseTranscript(text).entries
+		return nil
+	}
+	var entries []TranscriptEntry
+	if err := value.Decode(&entries); err != nil {
+		return err
+	}
+	t.entries = entries
+	return nil
+}
+
+// MarshalYAML stores the transcript as list of entries.
+func (t *Transcript) MarshalYAML() (any, error) {
+	return t.Entries(), nil
+}
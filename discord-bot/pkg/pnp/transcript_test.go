@@ -0,0 +1,73 @@
+package pnp
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseTranscript(t *testing.T) {
+	text := "Tharkhan: Wo sind wir?\nGameMaster: In der Gasse.\nDer Typ ist weg.\nAmon: Ok."
+	transcript := ParseTranscript(text)
+
+	entries := transcript.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries but got %d: %+v", len(entries), entries)
+	}
+	if entries[1].Name != "GameMaster" || entries[1].Text != "In der Gasse.\nDer Typ ist weg." {
+		t.Errorf("continuation line was not added to previous entry: %+v", entries[1])
+	}
+	if transcript.String() != text {
+		t.Errorf("expected plain text rendering to be unchanged but got:\n%s", transcript.String())
+	}
+}
+
+func TestTranscriptYaml(t *testing.T) {
+	start := time.Date(2024, 6, 1, 19, 30, 0, 0, time.UTC)
+	transcript := NewTranscript(
+		TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Start: start, End: start.Add(2 * time.Second), Source: SourceSTT, Text: "Foxie?"},
+		TranscriptEntry{SpeakerID: "Petra Gabriel", Name: "Petra Gabriel", Source: SourceNPC, Text: "Ja?"},
+	)
+	data, err := yaml.Marshal(transcript)
+	if err != nil {
+		t.Fatalf("could not marshal transcript: %v", err)
+	}
+
+	var restored Transcript
+	if err := yaml.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("could not unmarshal transcript: %v", err)
+	}
+	original, actual := transcript.Entries(), restored.Entries()
+	if len(actual) != len(original) {
+		t.Fatalf("expected %d entries but got %d", len(original), len(actual))
+	}
+	for i := range original {
+		if !original[i].Start.Equal(actual[i].Start) || !original[i].End.Equal(actual[i].End) {
+			t.Errorf("timestamps of entry %d changed: %+v != %+v", i, original[i], actual[i])
+		}
+		original[i].Start, original[i].End, actual[i].Start, actual[i].End = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+		if original[i] != actual[i] {
+			t.Errorf("entry %d changed: %+v != %+v", i, original[i], actual[i])
+		}
+	}
+}
+
+func TestLegacyTranscriptYaml(t *testing.T) {
+	data, err := os.ReadFile("testdata/legacy_transcript.yml")
+	if err != nil {
+		t.Fatalf("could not read legacy transcript fixture: %v", err)
+	}
+	c, err := CampaignFromYaml(data)
+	if err != nil {
+		t.Fatalf("could not read test campaign: %v", err)
+	}
+	if c.Transcript.Len() != 119 {
+		t.Errorf("expected 119 transcript entries but got %d", c.Transcript.Len())
+	}
+	if !strings.HasPrefix(c.CurrentTranscript(), "Tharkhan: Wo sind wir eigentlich gerade?") {
+		t.Errorf("unexpected start of transcript:\n%s", c.CurrentTranscript()[:100])
+	}
+}
@@ -0,0 +1,340 @@
+package pnp
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+)
+
+//go:embed turn_judge_prompt.tpl
+var turnJudgePromptText string
+
+var turnJudgePromptTemplate *template.Template
+
+func init() {
+	var err error
+	// Check if the judge prompt template can be resolved.
+	turnJudgePromptTemplate, err = template.New("turnJudge").Parse(turnJudgePromptText)
+	if err != nil {
+		panic(fmt.Errorf("could not parse turn judge prompt template: %w", err))
+	}
+	err = turnJudgePromptTemplate.Execute(io.Discard, turnJudgePromptData{
+		Actors: []*Actor{
+			{Name: "Foo", Aliases: []string{"Bar", "Baz"}},
+			{Name: "Mia"},
+		},
+	})
+	if err != nil {
+		panic(fmt.Errorf("turn judge prompt template can not be executed: %w", err))
+	}
+}
+
+// Turn that a TurnPolicy has to decide on.
+type Turn struct {
+	// Speaker of the latest line.
+	Speaker string
+	// Segment that the speaker said.
+	Segment string
+	// Candidates that may respond. Never includes the speaker.
+	Candidates []*Actor
+	// Transcript of the current session including the latest line.
+	Transcript string
+	// LastSpoken maps actor names to the transcript line index of their latest line. Actors that didn't speak yet are missing.
+	LastSpoken map[string]int
+	// Model of the campaign for policies that need to ask a LLM.
+	Model llm.ChatModel
+}
+
+// Addressed candidates that are mentioned by name or alias in the segment.
+func (t Turn) Addressed() []*Actor {
+	addressed := make([]*Actor, 0)
+	for _, actor := range t.Candidates {
+		if actor.IsAdressed(t.Segment) {
+			addressed = append(addressed, actor)
+		}
+	}
+	return addressed
+}
+
+// TurnPolicy decides which actor, if any, should respond to the latest line of a session.
+// NextActor is called concurrently for lines that arrive while an earlier turn is still being decided.
+type TurnPolicy interface {
+	// NextActor that should respond or nil if nobody should.
+	NextActor(ctx context.Context, turn Turn) (*Actor, error)
+}
+
+// lockedRand makes a rand.Rand safe for the concurrent turns of a policy.
+type lockedRand struct {
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func (r *lockedRand) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Intn(n)
+}
+
+func (r *lockedRand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rng.Float64()
+}
+
+// Names of the available turn policies as used in the campaign YAML.
+const (
+	TurnPolicyName           = "name"
+	TurnPolicyLLMJudge       = "llm-judge"
+	TurnPolicyWeightedRandom = "weighted-random"
+	TurnPolicyLeastRecent    = "least-recent"
+)
+
+// TurnPolicyConfig selects and configures the TurnPolicy of a campaign.
+type TurnPolicyConfig struct {
+	// Type of the policy. One of name (default), llm-judge, weighted-random or least-recent.
+	Type string `yaml:"type"`
+	// Model for the llm-judge. Defaults to the model of the campaign.
+	Model string `yaml:"model"`
+	// Window of transcript lines that the llm-judge will see. Defaults to 20.
+	Window int `yaml:"window"`
+	// Chance between 0 and 1 that a weighted-random actor speaks up without being addressed.
+	Chance float64 `yaml:"chance"`
+	// Weights of actors by name for weighted-random. Actors without weight have a weight of 1.
+	Weights map[string]float64 `yaml:"weights"`
+}
+
+// Validate that the config describes a valid policy.
+func (cfg TurnPolicyConfig) Validate() error {
+	var err error
+	switch cfg.Type {
+	case "", TurnPolicyName, TurnPolicyLLMJudge, TurnPolicyWeightedRandom, TurnPolicyLeastRecent:
+	default:
+		err = errors.Join(err, fmt.Errorf("unknown turn policy type %q", cfg.Type))
+	}
+	if cfg.Window < 0 {
+		err = errors.Join(err, fmt.Errorf("window must not be negative but is %d", cfg.Window))
+	}
+	if cfg.Chance < 0 || cfg.Chance > 1 {
+		err = errors.Join(err, fmt.Errorf("chance must be between 0 and 1 but is %v", cfg.Chance))
+	}
+	for name, weight := range cfg.Weights {
+		if weight < 0 {
+			err = errors.Join(err, fmt.Errorf("weight of %q must not be negative but is %v", name, weight))
+		}
+	}
+	return err
+}
+
+// Build the configured policy. All randomness will be taken from rng.
+func (cfg TurnPolicyConfig) Build(rng *rand.Rand) (TurnPolicy, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	switch cfg.Type {
+	case TurnPolicyLLMJudge:
+		return &LLMJudgePolicy{
+			Model:    cfg.Model,
+			Window:   cfg.Window,
+			Fallback: NewNamePolicy(rng),
+		}, nil
+	case TurnPolicyWeightedRandom:
+		return NewWeightedRandomPolicy(rng, cfg.Chance, cfg.Weights), nil
+	case TurnPolicyLeastRecent:
+		return NewLeastRecentPolicy(rng), nil
+	default:
+		return NewNamePolicy(rng), nil
+	}
+}
+
+// NamePolicy lets an actor respond if it is addressed by name or alias. If multiple actors are addressed a random one is chosen.
+type NamePolicy struct {
+	rng *lockedRand
+}
+
+// NewNamePolicy using rng to choose between multiple addressed actors.
+func NewNamePolicy(rng *rand.Rand) *NamePolicy {
+	return &NamePolicy{rng: &lockedRand{rng: rng}}
+}
+
+// NextActor implements TurnPolicy.
+func (p *NamePolicy) NextActor(_ context.Context, turn Turn) (*Actor, error) {
+	addressed := turn.Addressed()
+	if len(addressed) == 0 {
+		return nil, nil
+	}
+	return addressed[p.rng.Intn(len(addressed))], nil
+}
+
+// WeightedRandomPolicy chooses addressed actors by weight. If nobody is addressed any actor may speak up with the configured chance.
+type WeightedRandomPolicy struct {
+	rng     *lockedRand
+	chance  float64
+	weights map[string]float64
+}
+
+// NewWeightedRandomPolicy with the chance that an actor speaks up without being addressed and the weights of actors by name.
+// Actors without weight have a weight of 1.
+func NewWeightedRandomPolicy(rng *rand.Rand, chance float64, weights map[string]float64) *WeightedRandomPolicy {
+	return &WeightedRandomPolicy{
+		rng:     &lockedRand{rng: rng},
+		chance:  chance,
+		weights: weights,
+	}
+}
+
+// NextActor implements TurnPolicy.
+func (p *WeightedRandomPolicy) NextActor(_ context.Context, turn Turn) (*Actor, error) {
+	candidates := turn.Addressed()
+	if len(candidates) == 0 {
+		if p.rng.Float64() >= p.chance {
+			return nil, nil
+		}
+		candidates = turn.Candidates
+	}
+	return p.choose(candidates), nil
+}
+
+func (p *WeightedRandomPolicy) choose(candidates []*Actor) *Actor {
+	total := 0.0
+	for _, actor := range candidates {
+		total += p.weight(actor)
+	}
+	if total <= 0 {
+		return nil
+	}
+	roll := p.rng.Float64() * total
+	for _, actor := range candidates {
+		roll -= p.weight(actor)
+		if roll < 0 {
+			return actor
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+func (p *WeightedRandomPolicy) weight(actor *Actor) float64 {
+	if weight, ok := p.weights[actor.Name]; ok {
+		return weight
+	}
+	return 1
+}
+
+// LeastRecentPolicy lets the addressed actor respond that hasn't spoken for the longest time.
+type LeastRecentPolicy struct {
+	rng *lockedRand
+}
+
+// NewLeastRecentPolicy using rng to break ties.
+func NewLeastRecentPolicy(rng *rand.Rand) *LeastRecentPolicy {
+	return &LeastRecentPolicy{rng: &lockedRand{rng: rng}}
+}
+
+// NextActor implements TurnPolicy.
+func (p *LeastRecentPolicy) NextActor(_ context.Context, turn Turn) (*Actor, error) {
+	oldest := make([]*Actor, 0)
+	oldestLine := 0
+	for _, actor := range turn.Addressed() {
+		line, ok := turn.LastSpoken[actor.Name]
+		if !ok {
+			line = -1
+		}
+		switch {
+		case len(oldest) == 0 || line < oldestLine:
+			oldest = []*Actor{actor}
+			oldestLine = line
+		case line == oldestLine:
+			oldest = append(oldest, actor)
+		}
+	}
+	if len(oldest) == 0 {
+		return nil, nil
+	}
+	return oldest[p.rng.Intn(len(oldest))], nil
+}
+
+type turnJudgePromptData struct {
+	Actors []*Actor
+}
+
+// LLMJudgePolicy asks a LLM which actor should speak next based on the recent transcript.
+type LLMJudgePolicy struct {
+	// Model to use instead of the default model of the turns chat model.
+	Model string
+	// Window of the most recent transcript lines that will be judged. Defaults to 20.
+	Window int
+	// Fallback that decides if the LLM could not be asked. Can be nil.
+	Fallback TurnPolicy
+}
+
+// NextActor implements TurnPolicy.
+func (p *LLMJudgePolicy) NextActor(ctx context.Context, turn Turn) (*Actor, error) {
+	actor, err := p.judge(ctx, turn)
+	if err != nil && p.Fallback != nil {
+		return p.Fallback.NextActor(ctx, turn)
+	}
+	return actor, err
+}
+
+func (p *LLMJudgePolicy) judge(ctx context.Context, turn Turn) (*Actor, error) {
+	if len(turn.Candidates) == 0 {
+		return nil, nil
+	}
+	if turn.Model == nil {
+		return nil, errors.New("no chat model available to judge the turn")
+	}
+	systemPromptBuf := bytes.NewBuffer(make([]byte, 0))
+	if err := turnJudgePromptTemplate.Execute(systemPromptBuf, turnJudgePromptData{Actors: turn.Candidates}); err != nil {
+		return nil, fmt.Errorf("could not resolve turn judge prompt template: %w", err)
+	}
+	window := p.Window
+	if window == 0 {
+		window = 20
+	}
+	lines := strings.Split(turn.Transcript, "\n")
+	if len(lines) > window {
+		lines = lines[len(lines)-window:]
+	}
+
+	resp, err := turn.Model.Chat(ctx, llm.Request{
+		Model:     p.Model,
+		MaxTokens: 20,
+		Messages: []llm.Message{
+			{
+				Role:    llm.RoleSystem,
+				Content: systemPromptBuf.String(),
+			},
+			{
+				Role:    llm.RoleUser,
+				Content: strings.Join(lines, "\n"),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	answer := strings.ToLower(removeNonWordRunes(strings.TrimSpace(resp.Content)))
+	if answer == "none" || answer == "" {
+		return nil, nil
+	}
+	for _, actor := range turn.Candidates {
+		if strings.ToLower(removeNonWordRunes(actor.Name)) == answer {
+			return actor, nil
+		}
+	}
+	// Models sometimes answer with an alias or a single name part
+	for _, actor := range turn.Candidates {
+		if actor.IsAdressed(answer) {
+			return actor, nil
+		}
+	}
+	return nil, fmt.Errorf("turn judge answered with unknown actor %q", resp.Content)
+}
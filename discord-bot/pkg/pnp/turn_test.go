@@ -0,0 +1,210 @@
+package pnp
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func testTurn(segment string, actors ...*Actor) Turn {
+	return Turn{
+		Speaker:    "Tharkhan",
+		Segment:    segment,
+		Candidates: actors,
+		Transcript: "Tharkhan: " + segment,
+		LastSpoken: make(map[string]int),
+	}
+}
+
+func TestNamePolicy(t *testing.T) {
+	petra := NewActor("Petra Gabriel", "", "", "Foxie")
+	henri := NewActor("Henri", "", "")
+	policy := NewNamePolicy(rand.New(rand.NewSource(1)))
+
+	actor, err := policy.NextActor(context.Background(), testTurn("Wo sind wir?", petra, henri))
+	if err != nil || actor != nil {
+		t.Errorf("expected nobody to be chosen but got %v, %v", actor, err)
+	}
+	actor, err = policy.NextActor(context.Background(), testTurn("Henri, eine Karte bitte.", petra, henri))
+	if err != nil || actor != henri {
+		t.Errorf("expected Henri to be chosen but got %v, %v", actor, err)
+	}
+
+	chosen := make(map[*Actor]int)
+	for range 50 {
+		actor, _ := policy.NextActor(context.Background(), testTurn("Henri und Foxie, kommt her!", petra, henri))
+		chosen[actor]++
+	}
+	if chosen[petra] == 0 || chosen[henri] == 0 || len(chosen) != 2 {
+		t.Errorf("expected both addressed actors to be chosen randomly but got %v", chosen)
+	}
+}
+
+func TestNamePolicyIsDeterministic(t *testing.T) {
+	petra := NewActor("Petra Gabriel", "", "", "Foxie")
+	henri := NewActor("Henri", "", "")
+	first := NewNamePolicy(rand.New(rand.NewSource(42)))
+	second := NewNamePolicy(rand.New(rand.NewSource(42)))
+	for range 20 {
+		turn := testTurn("Henri und Foxie, kommt her!", petra, henri)
+		a, _ := first.NextActor(context.Background(), turn)
+		b, _ := second.NextActor(context.Background(), turn)
+		if a != b {
+			t.Fatalf("policies with the same seed chose %s and %s", a.Name, b.Name)
+		}
+	}
+}
+
+func TestWeightedRandomPolicy(t *testing.T) {
+	petra := NewActor("Petra Gabriel", "", "", "Foxie")
+	henri := NewActor("Henri", "", "")
+	silent := NewWeightedRandomPolicy(rand.New(rand.NewSource(1)), 0, nil)
+	actor, err := silent.NextActor(context.Background(), testTurn("Wo sind wir?", petra, henri))
+	if err != nil || actor != nil {
+		t.Errorf("expected nobody to speak up with chance 0 but got %v, %v", actor, err)
+	}
+	actor, err = silent.NextActor(context.Background(), testTurn("Hallo Foxie", petra, henri))
+	if err != nil || actor != petra {
+		t.Errorf("expected addressed Petra to respond but got %v, %v", actor, err)
+	}
+
+	chatty := NewWeightedRandomPolicy(rand.New(rand.NewSource(1)), 1, map[string]float64{"Henri": 0})
+	for range 20 {
+		actor, err := chatty.NextActor(context.Background(), testTurn("Wo sind wir?", petra, henri))
+		if err != nil || actor != petra {
+			t.Fatalf("expected only Petra to speak up as Henri has weight 0 but got %v, %v", actor, err)
+		}
+	}
+}
+
+func TestLeastRecentPolicy(t *testing.T) {
+	petra := NewActor("Petra Gabriel", "", "", "Foxie")
+	henri := NewActor("Henri", "", "")
+	policy := NewLeastRecentPolicy(rand.New(rand.NewSource(1)))
+
+	turn := testTurn("Henri und Foxie, kommt her!", petra, henri)
+	turn.LastSpoken["Henri"] = 3
+	actor, err := policy.NextActor(context.Background(), turn)
+	if err != nil || actor != petra {
+		t.Errorf("expected Petra who never spoke to be chosen but got %v, %v", actor, err)
+	}
+	turn.LastSpoken["Petra Gabriel"] = 7
+	actor, err = policy.NextActor(context.Background(), turn)
+	if err != nil || actor != henri {
+		t.Errorf("expected Henri who spoke longer ago to be chosen but got %v, %v", actor, err)
+	}
+	actor, err = policy.NextActor(context.Background(), testTurn("Wo sind wir?", petra, henri))
+	if err != nil || actor != nil {
+		t.Errorf("expected nobody to be chosen but got %v, %v", actor, err)
+	}
+}
+
+func TestLLMJudgePolicy(t *testing.T) {
+	petra := NewActor("Petra Gabriel", "", "", "Foxie")
+	henri := NewActor("Henri", "", "")
+	tests := map[string]*Actor{
+		"Petra Gabriel": petra,
+		"Henri.":        henri,
+		"Foxie":         petra,
+		"NONE":          nil,
+	}
+	for answer, expected := range tests {
+		t.Run("answer "+answer, func(t *testing.T) {
+			turn := testTurn("Wo sind wir?", petra, henri)
+			turn.Model = newChatStandIn(t, answer, nil)
+			actor, err := (&LLMJudgePolicy{}).NextActor(context.Background(), turn)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actor != expected {
+				t.Errorf("expected %v to be chosen but got %v", expected, actor)
+			}
+		})
+	}
+
+	turn := testTurn("Henri?", petra, henri)
+	turn.Model = newChatStandIn(t, "The innkeeper", nil)
+	if _, err := (&LLMJudgePolicy{}).NextActor(context.Background(), turn); err == nil {
+		t.Error("expected an error for an unknown actor without fallback")
+	}
+	actor, err := (&LLMJudgePolicy{Fallback: NewNamePolicy(rand.New(rand.NewSource(1)))}).NextActor(context.Background(), turn)
+	if err != nil || actor != henri {
+		t.Errorf("expected fallback to choose Henri but got %v, %v", actor, err)
+	}
+}
+
+// blockingPolicy lets nobody respond once it is released and reports every turn that it starts to decide.
+type blockingPolicy struct {
+	started, release chan struct{}
+}
+
+func (p blockingPolicy) NextActor(context.Context, Turn) (*Actor, error) {
+	p.started <- struct{}{}
+	<-p.release
+	return nil, nil
+}
+
+func TestTurnsAreDecidedConcurrently(t *testing.T) {
+	c := testCampaign(t, "")
+	policy := blockingPolicy{started: make(chan struct{}, 2), release: make(chan struct{})}
+	defer close(policy.release)
+	c.SetTurnPolicy(policy)
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie?"})
+	c.HandleText(TranscriptEntry{SpeakerID: "5678", Name: "Amon", Source: SourceSTT, Text: "Foxie!"})
+
+	for i := range 2 {
+		select {
+		case <-policy.started:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("turn %d was not decided while the first one was still pending", i+1)
+		}
+	}
+}
+
+func TestTurnPolicyConfig(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	valid := []struct {
+		cfg      TurnPolicyConfig
+		expected string
+	}{
+		{TurnPolicyConfig{}, "*pnp.NamePolicy"},
+		{TurnPolicyConfig{Type: TurnPolicyName}, "*pnp.NamePolicy"},
+		{TurnPolicyConfig{Type: TurnPolicyLLMJudge, Window: 5}, "*pnp.LLMJudgePolicy"},
+		{TurnPolicyConfig{Type: TurnPolicyWeightedRandom, Chance: 0.3}, "*pnp.WeightedRandomPolicy"},
+		{TurnPolicyConfig{Type: TurnPolicyLeastRecent}, "*pnp.LeastRecentPolicy"},
+	}
+	for _, test := range valid {
+		policy, err := test.cfg.Build(rng)
+		if err != nil {
+			t.Errorf("unexpected error for config %+v: %v", test.cfg, err)
+			continue
+		}
+		if actual := fmt.Sprintf("%T", policy); actual != test.expected {
+			t.Errorf("expected config %+v to build a %s but got %s", test.cfg, test.expected, actual)
+		}
+	}
+
+	invalid := []TurnPolicyConfig{
+		{Type: "loudest"},
+		{Window: -1},
+		{Chance: 1.5},
+		{Weights: map[string]float64{"Henri": -1}},
+	}
+	for _, cfg := range invalid {
+		if _, err := cfg.Build(rng); err == nil {
+			t.Errorf("expected config %+v to be invalid", cfg)
+		}
+	}
+
+	_, err := CampaignFromYaml([]byte(testCampaignYaml + `turnPolicy:
+  type: weighted-random
+  weights:
+    Henri: 2
+`))
+	if err == nil {
+		t.Error("expected campaign with weight for unknown actor to be invalid")
+	}
+}
@@ -0,0 +1,75 @@
+package pnp
+
+import (
+	"log/slog"
+	"unicode/utf8"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/usage"
+)
+
+// Names under which usage that doesn't belong to an actor is recorded.
+const (
+	UsageSummary    = "summary"
+	UsageTurnPolicy = "turn policy"
+	UsageVectorDB   = "vector db"
+	UsageAnnouncer  = "announcer"
+	UsageNarrator   = "narrator"
+	UsageClassifier = "classifier"
+	UsageCorrection = "correction"
+)
+
+// SetUsageLedger that records the usage of this campaign. The running session is started in the ledger.
+// Defaults to a ledger that is only kept in memory.
+func (c *Campaign) SetUsageLedger(ledger *usage.Ledger) {
+	ledger.StartSession(c.StartedAt)
+	c.ledger = ledger
+}
+
+// SetBudget of this campaign. Defaults to usage.DefaultBudget().
+func (c *Campaign) SetBudget(budget usage.Budget) {
+	c.budget = budget
+}
+
+// Usage of this campaign over all sessions recorded in its ledger.
+func (c *Campaign) Usage() (usage.Report, usage.Budget) {
+	return c.ledger.Report(), c.budget
+}
+
+// RecordSpeech that was synthesized for the actor or other source with the given name.
+func (c *Campaign) RecordSpeech(name, text string) {
+	c.ledger.Record(name, "", usage.Counts{TTSCharacters: utf8.RuneCountInString(text)})
+}
+
+// recordEmbedding of text that the vector DB vectorizes.
+func (c *Campaign) recordEmbedding(text string) {
+	c.ledger.Record(UsageVectorDB, "", usage.Counts{EmbeddingTokens: llm.ApproxTokenizer{}.CountTokens(text)})
+}
+
+// model that records its usage under the given name. Returns nil if the campaign has no chat model.
+func (c *Campaign) model(name string) llm.ChatModel {
+	if c.chatModel == nil {
+		return nil
+	}
+	return usage.Meter(c.chatModel, c.ledger, name)
+}
+
+// applyBudget to the actor. Returns a copy of the actor that uses the cheap model once the soft limit is reached
+// and false if the hard limit is reached, so the actor must stay silent.
+func (c *Campaign) applyBudget(actor *Actor) (*Actor, bool) {
+	switch c.budget.Level(c.ledger.Models()) {
+	case usage.HardLimit:
+		slog.Warn("hard budget limit reached, actors stay silent", "campaign", c.Name, "name", actor.Name, "hardLimit", c.budget.Hard)
+		return nil, false
+	case usage.SoftLimit:
+		if c.budget.CheapModel == "" || actor.Model == c.budget.CheapModel {
+			return actor, true
+		}
+		slog.Info("soft budget limit reached, actor uses cheap model", "campaign", c.Name, "name", actor.Name, "model", c.budget.CheapModel)
+		cheap := *actor
+		cheap.Model = c.budget.CheapModel
+		return &cheap, true
+	default:
+		return actor, true
+	}
+}
@@ -0,0 +1,68 @@
+package pnp
+
+import (
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/usage"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestBudgetLimits(t *testing.T) {
+	budget := usage.Budget{Soft: 1, Hard: 2, CheapModel: "cheap-model", Prices: usage.Prices{usage.DefaultModel: {PromptTokens: 1_000_000}}}
+	tests := []struct {
+		spent    int
+		model    string
+		responds bool
+	}{
+		{0, "test-model", true},
+		{1, "cheap-model", true},
+		{2, "", false},
+	}
+	for _, test := range tests {
+		var received openai.ChatCompletionRequest
+		c := campaignFromYaml(t, "", "Hallo!", &received)
+		c.SetBudget(budget)
+		ledger := usage.NewLedger()
+		ledger.Record("Petra Gabriel", "test-model", usage.Counts{PromptTokens: test.spent})
+		c.SetUsageLedger(ledger)
+
+		c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie?"})
+
+		select {
+		case resp := <-c.C():
+			resp.Text()
+			if !test.responds {
+				t.Errorf("expected actors to be silent after spending %d dollars", test.spent)
+			} else if received.Model != test.model {
+				t.Errorf("expected model %s after spending %d dollars but got %s", test.model, test.spent, received.Model)
+			}
+		case <-time.After(300 * time.Millisecond):
+			if test.responds {
+				t.Errorf("expected actor to respond after spending %d dollars", test.spent)
+			}
+		}
+	}
+}
+
+func TestUsageIsRecordedPerActor(t *testing.T) {
+	c := testCampaign(t, "Zum Sonnenturm natürlich!")
+
+	c.HandleText(TranscriptEntry{SpeakerID: "1234", Name: "Tharkhan", Source: SourceSTT, Text: "Foxie, wo willst du hin?"})
+	select {
+	case resp := <-c.C():
+		c.RecordSpeech(resp.Actor.Name, resp.Text())
+	case <-time.After(5 * time.Second):
+		t.Fatal("actor did not respond in time")
+	}
+
+	report, _ := c.Usage()
+	petra := report.Current().Actors["Petra Gabriel"].Total()
+	if petra.PromptTokens == 0 || petra.CompletionTokens == 0 {
+		t.Errorf("expected the tokens of the response to be recorded but got %+v", petra)
+	}
+	if petra.TTSCharacters != utf8.RuneCountInString("Zum Sonnenturm natürlich!") {
+		t.Errorf("expected the TTS characters to be recorded but got %+v", petra)
+	}
+}
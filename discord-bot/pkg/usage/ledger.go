@@ -0,0 +1,169 @@
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Report of the usage of a campaign.
+type Report struct {
+	// Total of all sessions.
+	Total Counts `yaml:"total"`
+	// Models that produced the counts over all sessions.
+	Models ModelCounts `yaml:"models"`
+	// Actors and other sources like summaries by name over all sessions.
+	Actors map[string]ModelCounts `yaml:"actors"`
+	// Sessions of the campaign. The last one is the current session.
+	Sessions []Session `yaml:"sessions"`
+}
+
+// Session of a campaign.
+type Session struct {
+	// StartedAt identifies the session.
+	StartedAt time.Time `yaml:"startedAt"`
+	// Total of the session.
+	Total Counts `yaml:"total"`
+	// Models that produced the counts of the session.
+	Models ModelCounts `yaml:"models"`
+	// Actors and other sources like summaries by name.
+	Actors map[string]ModelCounts `yaml:"actors"`
+}
+
+// Current session of the report. Zero if no session was started yet.
+func (r Report) Current() Session {
+	if len(r.Sessions) == 0 {
+		return Session{}
+	}
+	return r.Sessions[len(r.Sessions)-1]
+}
+
+// Ledger records the usage of a campaign. It is safe for concurrent use.
+type Ledger struct {
+	mu     sync.Mutex
+	path   string
+	report Report
+}
+
+// NewLedger that is only kept in memory.
+func NewLedger() *Ledger {
+	return &Ledger{
+		report: Report{Actors: make(map[string]ModelCounts)},
+	}
+}
+
+// OpenLedger stored at path. A new ledger is created if the file doesn't exist yet. Save will write to the same path.
+func OpenLedger(path string) (*Ledger, error) {
+	l := NewLedger()
+	l.path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read usage ledger: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &l.report); err != nil {
+		return nil, fmt.Errorf("could not parse usage ledger %s: %w", path, err)
+	}
+	if l.report.Actors == nil {
+		l.report.Actors = make(map[string]ModelCounts)
+	}
+	return l, nil
+}
+
+// StartSession that started at the given time. Continues the last session if it started at the same time, so resumed sessions are not counted twice.
+func (l *Ledger) StartSession(startedAt time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n := len(l.report.Sessions); n > 0 && l.report.Sessions[n-1].StartedAt.Equal(startedAt) {
+		return
+	}
+	l.report.Sessions = append(l.report.Sessions, Session{
+		StartedAt: startedAt,
+		Actors:    make(map[string]ModelCounts),
+	})
+}
+
+// Record the counts that the model produced for the actor or other source with the given name.
+// Use an empty model for units that no chat model produced.
+func (l *Ledger) Record(name, model string, counts Counts) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.report.Total = l.report.Total.Add(counts)
+	l.report.Models = l.report.Models.Add(model, counts)
+	l.report.Actors[name] = l.report.Actors[name].Add(model, counts)
+	if n := len(l.report.Sessions); n > 0 {
+		session := &l.report.Sessions[n-1]
+		session.Total = session.Total.Add(counts)
+		session.Models = session.Models.Add(model, counts)
+		if session.Actors == nil {
+			session.Actors = make(map[string]ModelCounts)
+		}
+		session.Actors[name] = session.Actors[name].Add(model, counts)
+	}
+}
+
+// Total of all sessions.
+func (l *Ledger) Total() Counts {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.report.Total
+}
+
+// Models that produced the counts over all sessions. The counts are a copy.
+func (l *Ledger) Models() ModelCounts {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return maps.Clone(l.report.Models)
+}
+
+// Report of the recorded usage. The report is a copy.
+func (l *Ledger) Report() Report {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	report := Report{
+		Total:    l.report.Total,
+		Models:   maps.Clone(l.report.Models),
+		Actors:   maps.Clone(l.report.Actors),
+		Sessions: make([]Session, len(l.report.Sessions)),
+	}
+	for i, session := range l.report.Sessions {
+		session.Models = maps.Clone(session.Models)
+		session.Actors = maps.Clone(session.Actors)
+		report.Sessions[i] = session
+	}
+	return report
+}
+
+// Save the ledger to the path it was opened from. Does nothing if the ledger is only kept in memory.
+func (l *Ledger) Save() error {
+	if l.path == "" {
+		return nil
+	}
+	data, err := yaml.Marshal(l.Report())
+	if err != nil {
+		return fmt.Errorf("could not marshal usage ledger: %w", err)
+	}
+	// Write to a temporary file first so a crash midway never corrupts the previous ledger.
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), l.path)
+}
@@ -0,0 +1,61 @@
+package usage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLedgerRecordsPerSessionAndActor(t *testing.T) {
+	l := NewLedger()
+	first := time.Date(2024, 6, 1, 19, 0, 0, 0, time.UTC)
+	l.StartSession(first)
+	l.Record("Petra", "gpt-4o", Counts{PromptTokens: 100, CompletionTokens: 10})
+	l.StartSession(first.Add(7 * 24 * time.Hour))
+	l.Record("Petra", "gpt-4o-mini", Counts{PromptTokens: 50})
+	l.Record("summary", "gpt-4o", Counts{PromptTokens: 20})
+
+	report := l.Report()
+	if report.Total != (Counts{PromptTokens: 170, CompletionTokens: 10}) {
+		t.Errorf("unexpected total %+v", report.Total)
+	}
+	if report.Actors["Petra"].Total() != (Counts{PromptTokens: 150, CompletionTokens: 10}) {
+		t.Errorf("unexpected total of Petra %+v", report.Actors["Petra"])
+	}
+	if report.Models["gpt-4o"] != (Counts{PromptTokens: 120, CompletionTokens: 10}) || report.Models["gpt-4o-mini"] != (Counts{PromptTokens: 50}) {
+		t.Errorf("unexpected counts per model %+v", report.Models)
+	}
+	if len(report.Sessions) != 2 {
+		t.Fatalf("expected 2 sessions but got %d", len(report.Sessions))
+	}
+	current := report.Current()
+	if current.Total != (Counts{PromptTokens: 70}) || current.Actors["Petra"]["gpt-4o-mini"] != (Counts{PromptTokens: 50}) {
+		t.Errorf("unexpected current session %+v", current)
+	}
+}
+
+func TestLedgerSaveAndOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Test-usage.yml")
+	l, err := OpenLedger(path)
+	if err != nil {
+		t.Fatalf("could not open new ledger: %v", err)
+	}
+	startedAt := time.Date(2024, 6, 1, 19, 0, 0, 0, time.UTC)
+	l.StartSession(startedAt)
+	l.Record("Petra", "", Counts{TTSCharacters: 42})
+	if err := l.Save(); err != nil {
+		t.Fatalf("could not save ledger: %v", err)
+	}
+
+	restored, err := OpenLedger(path)
+	if err != nil {
+		t.Fatalf("could not open saved ledger: %v", err)
+	}
+	// A resumed session must continue instead of starting over
+	restored.StartSession(startedAt)
+	restored.Record("Petra", "", Counts{TTSCharacters: 8})
+	report := restored.Report()
+	if len(report.Sessions) != 1 || report.Current().Actors["Petra"][""].TTSCharacters != 50 || report.Total.TTSCharacters != 50 {
+		t.Errorf("unexpected restored report %+v", report)
+	}
+}
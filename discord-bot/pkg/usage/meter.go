@@ -0,0 +1,109 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+)
+
+// Meter wraps the model so the usage of every request is recorded in the ledger under the given name and the model of the request.
+// If the API doesn't report the usage it will be estimated with llm.ApproxTokenizer.
+func Meter(model llm.ChatModel, ledger *Ledger, name string) llm.ChatModel {
+	return &meteredModel{
+		model:  model,
+		ledger: ledger,
+		name:   name,
+	}
+}
+
+type meteredModel struct {
+	model  llm.ChatModel
+	ledger *Ledger
+	name   string
+}
+
+// Chat implements llm.ChatModel.
+func (m *meteredModel) Chat(ctx context.Context, req llm.Request) (llm.Response, error) {
+	resp, err := m.model.Chat(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	m.record(req, resp.Usage, resp.Content, resp.ToolCalls)
+	return resp, nil
+}
+
+// ChatStream implements llm.ChatModel.
+func (m *meteredModel) ChatStream(ctx context.Context, req llm.Request) (llm.Stream, error) {
+	stream, err := m.model.ChatStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &meteredStream{Stream: stream, model: m, req: req}, nil
+}
+
+// record the usage of the request under the model that answered it. Estimates it if the API didn't report it.
+func (m *meteredModel) record(req llm.Request, usage llm.Usage, content string, toolCalls []llm.ToolCall) {
+	if usage == (llm.Usage{}) {
+		usage = estimate(req, content, toolCalls)
+	}
+	m.ledger.Record(m.name, llm.ResolveModel(m.model, req.Model), Counts{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+	})
+}
+
+func estimate(req llm.Request, content string, toolCalls []llm.ToolCall) llm.Usage {
+	var tokenizer llm.ApproxTokenizer
+	var usage llm.Usage
+	for _, msg := range req.Messages {
+		usage.PromptTokens += tokenizer.CountTokens(msg.Content)
+		for _, call := range msg.ToolCalls {
+			usage.PromptTokens += tokenizer.CountTokens(call.Name + call.Arguments)
+		}
+	}
+	for _, tool := range req.Tools {
+		usage.PromptTokens += tokenizer.CountTokens(tool.Name + tool.Description + string(tool.Parameters))
+	}
+	usage.CompletionTokens = tokenizer.CountTokens(content)
+	for _, call := range toolCalls {
+		usage.CompletionTokens += tokenizer.CountTokens(call.Name + call.Arguments)
+	}
+	return usage
+}
+
+// meteredStream records the usage once the stream has ended or was closed early.
+type meteredStream struct {
+	llm.Stream
+	model    *meteredModel
+	req      llm.Request
+	content  strings.Builder
+	recorded bool
+}
+
+// Recv implements llm.Stream.
+func (s *meteredStream) Recv() (string, error) {
+	piece, err := s.Stream.Recv()
+	s.content.WriteString(piece)
+	if errors.Is(err, io.EOF) {
+		s.record()
+	}
+	return piece, err
+}
+
+// Close implements llm.Stream.
+func (s *meteredStream) Close() error {
+	// Aborted streams are billed for what has been generated so far.
+	s.record()
+	return s.Stream.Close()
+}
+
+func (s *meteredStream) record() {
+	if s.recorded {
+		return
+	}
+	s.recorded = true
+	s.model.record(s.req, s.Stream.Usage(), s.content.String(), s.Stream.ToolCalls())
+}
@@ -0,0 +1,98 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/MrWong99/TaileVoices/discord_bot/pkg/llm"
+)
+
+// fakeModel answers with the given pieces and reports the given usage.
+type fakeModel struct {
+	pieces []string
+	usage  llm.Usage
+}
+
+func (m fakeModel) Model() string { return "test-model" }
+
+func (m fakeModel) Chat(context.Context, llm.Request) (llm.Response, error) {
+	content := ""
+	for _, piece := range m.pieces {
+		content += piece
+	}
+	return llm.Response{Content: content, Usage: m.usage}, nil
+}
+
+func (m fakeModel) ChatStream(context.Context, llm.Request) (llm.Stream, error) {
+	return &fakeStream{pieces: m.pieces, usage: m.usage}, nil
+}
+
+type fakeStream struct {
+	pieces []string
+	usage  llm.Usage
+}
+
+func (s *fakeStream) Recv() (string, error) {
+	if len(s.pieces) == 0 {
+		return "", io.EOF
+	}
+	piece := s.pieces[0]
+	s.pieces = s.pieces[1:]
+	return piece, nil
+}
+
+func (s *fakeStream) ToolCalls() []llm.ToolCall { return nil }
+
+func (s *fakeStream) Usage() llm.Usage {
+	if len(s.pieces) > 0 {
+		return llm.Usage{} // Only reported at the end
+	}
+	return s.usage
+}
+
+func (s *fakeStream) Close() error { return nil }
+
+var testRequest = llm.Request{Messages: []llm.Message{{Role: llm.RoleUser, Content: "Tharkhan: Hello there!"}}}
+
+func TestMeterRecordsReportedUsage(t *testing.T) {
+	l := NewLedger()
+	model := Meter(fakeModel{pieces: []string{"Hi ", "Tharkhan!"}, usage: llm.Usage{PromptTokens: 30, CompletionTokens: 4}}, l, "Petra")
+
+	if _, err := model.Chat(context.Background(), testRequest); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cheapRequest := testRequest
+	cheapRequest.Model = "cheap-model"
+	stream, _ := model.ChatStream(context.Background(), cheapRequest)
+	for {
+		if _, err := stream.Recv(); errors.Is(err, io.EOF) {
+			break
+		}
+	}
+	stream.Close()
+
+	if total := l.Report().Actors["Petra"].Total(); total != (Counts{PromptTokens: 60, CompletionTokens: 8}) {
+		t.Errorf("expected both requests to be recorded once but got %+v", total)
+	}
+	models := l.Models()
+	if models["test-model"] != (Counts{PromptTokens: 30, CompletionTokens: 4}) || models["cheap-model"] != (Counts{PromptTokens: 30, CompletionTokens: 4}) {
+		t.Errorf("expected the requests to be recorded under their models but got %+v", models)
+	}
+}
+
+func TestMeterEstimatesUnreportedUsage(t *testing.T) {
+	l := NewLedger()
+	model := Meter(fakeModel{pieces: []string{"Hi ", "Tharkhan! ", "How are you?"}, usage: llm.Usage{PromptTokens: 30, CompletionTokens: 4}}, l, "Petra")
+
+	// Closing early means the API never reported the usage
+	stream, _ := model.ChatStream(context.Background(), testRequest)
+	stream.Recv()
+	stream.Close()
+
+	expected := Counts{PromptTokens: llm.ApproxTokenizer{}.CountTokens(testRequest.Messages[0].Content), CompletionTokens: 1}
+	if total := l.Total(); total != expected {
+		t.Errorf("expected the estimate %+v but got %+v", expected, total)
+	}
+}
@@ -0,0 +1,181 @@
+// Package usage accounts for the costs of campaigns like generated tokens, synthesized speech and vectorized text.
+package usage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Counts of billable units.
+type Counts struct {
+	// PromptTokens sent to the chat model.
+	PromptTokens int `yaml:"promptTokens,omitempty"`
+	// CompletionTokens generated by the chat model.
+	CompletionTokens int `yaml:"completionTokens,omitempty"`
+	// TTSCharacters converted to speech.
+	TTSCharacters int `yaml:"ttsCharacters,omitempty"`
+	// EmbeddingTokens of text that the vector DB vectorized. This is an estimate.
+	EmbeddingTokens int `yaml:"embeddingTokens,omitempty"`
+}
+
+// Add the other counts and return the sum.
+func (c Counts) Add(other Counts) Counts {
+	return Counts{
+		PromptTokens:     c.PromptTokens + other.PromptTokens,
+		CompletionTokens: c.CompletionTokens + other.CompletionTokens,
+		TTSCharacters:    c.TTSCharacters + other.TTSCharacters,
+		EmbeddingTokens:  c.EmbeddingTokens + other.EmbeddingTokens,
+	}
+}
+
+// Cost of the counts in US dollars.
+func (c Counts) Cost(prices ModelPrices) float64 {
+	return (float64(c.PromptTokens)*prices.PromptTokens +
+		float64(c.CompletionTokens)*prices.CompletionTokens +
+		float64(c.TTSCharacters)*prices.TTSCharacters +
+		float64(c.EmbeddingTokens)*prices.EmbeddingTokens) / 1_000_000
+}
+
+// String renders the counts like "1200 prompt tokens, 300 completion tokens, 800 TTS characters, 50 embedding tokens".
+func (c Counts) String() string {
+	return fmt.Sprintf("%d prompt tokens, %d completion tokens, %d TTS characters, %d embedding tokens",
+		c.PromptTokens, c.CompletionTokens, c.TTSCharacters, c.EmbeddingTokens)
+}
+
+// ModelCounts of billable units by the model that produced them. Units that no chat model produced,
+// like TTS characters and embedding tokens, are counted under the empty model.
+type ModelCounts map[string]Counts
+
+// Add the counts of the model and return the sum. The receiver is not modified.
+func (m ModelCounts) Add(model string, counts Counts) ModelCounts {
+	sum := make(ModelCounts, len(m)+1)
+	for name, c := range m {
+		sum[name] = c
+	}
+	sum[model] = sum[model].Add(counts)
+	return sum
+}
+
+// Total of all models.
+func (m ModelCounts) Total() Counts {
+	var total Counts
+	for _, counts := range m {
+		total = total.Add(counts)
+	}
+	return total
+}
+
+// Cost of the counts in US dollars with the prices of the model that produced them.
+func (m ModelCounts) Cost(prices Prices) float64 {
+	cost := 0.0
+	for model, counts := range m {
+		cost += counts.Cost(prices.For(model))
+	}
+	return cost
+}
+
+// ModelPrices in US dollars per million units.
+type ModelPrices struct {
+	PromptTokens     float64 `yaml:"promptTokens"`
+	CompletionTokens float64 `yaml:"completionTokens"`
+	TTSCharacters    float64 `yaml:"ttsCharacters"`
+	EmbeddingTokens  float64 `yaml:"embeddingTokens"`
+}
+
+// DefaultModel is the entry of Prices that is used for all models without an own entry and for units that no chat model produced.
+const DefaultModel = "default"
+
+// Prices by model. A model uses the entry with the longest name that its own name starts with,
+// so "gpt-4o-mini" also prices "gpt-4o-mini-2024-07-18".
+type Prices map[string]ModelPrices
+
+// For returns the prices of the model. Falls back to the DefaultModel entry and then to DefaultPrices.
+func (p Prices) For(model string) ModelPrices {
+	match := ""
+	for name := range p {
+		if name != DefaultModel && len(name) > len(match) && strings.HasPrefix(model, name) {
+			match = name
+		}
+	}
+	if match != "" {
+		return p[match]
+	}
+	if prices, ok := p[DefaultModel]; ok {
+		return prices
+	}
+	return DefaultPrices.For(model)
+}
+
+// DefaultPrices of gpt-4o and gpt-4o-mini, with tts-1-hd and text-embedding-ada-002 for speech and embeddings.
+// Other models are priced like gpt-4o.
+var DefaultPrices = Prices{
+	DefaultModel: {
+		PromptTokens:     5,
+		CompletionTokens: 15,
+		TTSCharacters:    30,
+		EmbeddingTokens:  0.1,
+	},
+	"gpt-4o-mini": {
+		PromptTokens:     0.15,
+		CompletionTokens: 0.6,
+		TTSCharacters:    30,
+		EmbeddingTokens:  0.1,
+	},
+}
+
+// Level of spending compared to a budget.
+type Level int
+
+const (
+	// WithinBudget if no limit has been reached.
+	WithinBudget Level = iota
+	// SoftLimit reached, actors should switch to a cheaper model.
+	SoftLimit
+	// HardLimit reached, actors should stay silent.
+	HardLimit
+)
+
+// Budget of a campaign in US dollars.
+type Budget struct {
+	// Soft limit at which actors switch to the CheapModel. 0 disables the limit.
+	Soft float64
+	// Hard limit at which actors stop responding. 0 disables the limit.
+	Hard float64
+	// CheapModel that actors use once the soft limit is reached. Actors keep their model if empty.
+	CheapModel string
+	// Prices to calculate the costs with. Uses DefaultPrices if empty.
+	Prices Prices
+}
+
+// Level of spending for the given counts by model.
+func (b Budget) Level(counts ModelCounts) Level {
+	cost := counts.Cost(b.EffectivePrices())
+	switch {
+	case b.Hard > 0 && cost >= b.Hard:
+		return HardLimit
+	case b.Soft > 0 && cost >= b.Soft:
+		return SoftLimit
+	default:
+		return WithinBudget
+	}
+}
+
+// EffectivePrices of the budget.
+func (b Budget) EffectivePrices() Prices {
+	if len(b.Prices) == 0 {
+		return DefaultPrices
+	}
+	return b.Prices
+}
+
+var defaultBudget Budget
+
+// SetDefaultBudget to override the DefaultBudget().
+func SetDefaultBudget(budget Budget) {
+	defaultBudget = budget
+}
+
+// DefaultBudget that campaigns use. Has no limits if SetDefaultBudget was not called yet.
+func DefaultBudget() Budget {
+	return defaultBudget
+}
@@ -0,0 +1,73 @@
+package usage
+
+import "testing"
+
+func TestCountsCost(t *testing.T) {
+	counts := Counts{PromptTokens: 1_000_000, CompletionTokens: 100_000, TTSCharacters: 10_000, EmbeddingTokens: 1_000_000}
+	prices := ModelPrices{PromptTokens: 5, CompletionTokens: 15, TTSCharacters: 30, EmbeddingTokens: 0.1}
+	if cost := counts.Cost(prices); cost < 6.89 || cost > 6.91 {
+		t.Errorf("expected a cost of 6.90 but got %v", cost)
+	}
+}
+
+func TestModelCountsCost(t *testing.T) {
+	prices := Prices{
+		DefaultModel:  {PromptTokens: 5, TTSCharacters: 30},
+		"cheap-model": {PromptTokens: 0.5},
+	}
+	counts := ModelCounts{
+		"test-model":  {PromptTokens: 1_000_000},
+		"cheap-model": {PromptTokens: 1_000_000},
+		"":            {TTSCharacters: 100_000},
+	}
+	if cost := counts.Cost(prices); cost < 8.49 || cost > 8.51 {
+		t.Errorf("expected a cost of 8.50 but got %v", cost)
+	}
+}
+
+func TestPricesFor(t *testing.T) {
+	prices := Prices{
+		DefaultModel:  {PromptTokens: 1},
+		"gpt-4o":      {PromptTokens: 2},
+		"gpt-4o-mini": {PromptTokens: 3},
+	}
+	tests := []struct {
+		model  string
+		prompt float64
+	}{
+		{"gpt-4o", 2},
+		{"gpt-4o-2024-08-06", 2},
+		{"gpt-4o-mini-2024-07-18", 3},
+		{"llama3", 1},
+		{"", 1},
+	}
+	for _, test := range tests {
+		if p := prices.For(test.model); p.PromptTokens != test.prompt {
+			t.Errorf("expected prompt price %v for model %q but got %v", test.prompt, test.model, p.PromptTokens)
+		}
+	}
+	if p := (Prices{"llama3": {}}).For("gpt-4o-mini"); p != DefaultPrices["gpt-4o-mini"] {
+		t.Errorf("expected prices without default entry to fall back to DefaultPrices but got %+v", p)
+	}
+}
+
+func TestBudgetLevel(t *testing.T) {
+	budget := Budget{Soft: 1, Hard: 2, Prices: Prices{DefaultModel: {PromptTokens: 1_000_000}}}
+	tests := []struct {
+		tokens int
+		level  Level
+	}{
+		{0, WithinBudget},
+		{1, SoftLimit},
+		{2, HardLimit},
+		{3, HardLimit},
+	}
+	for _, test := range tests {
+		if level := budget.Level(ModelCounts{"test-model": {PromptTokens: test.tokens}}); level != test.level {
+			t.Errorf("expected level %d for %d dollars but got %d", test.level, test.tokens, level)
+		}
+	}
+	if level := (Budget{}).Level(ModelCounts{"test-model": {PromptTokens: 1_000_000_000}}); level != WithinBudget {
+		t.Errorf("expected a budget without limits to never be exceeded but got %d", level)
+	}
+}
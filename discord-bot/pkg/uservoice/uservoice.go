@@ -32,6 +32,7 @@ type TextSegment struct {
 // Voice represents a voice processing instance for a single user.
 type Voice struct {
 	Username    string           // Username of the user
+	UserID      string           // Discord user ID of the user
 	SSRC        uint32           // SSRC identifier
 	decoder     *opus.Decoder    // Opus decoder
 	stt         *audio.STT       // Speech-to-text processor
@@ -129,6 +130,11 @@ func (v *Voice) processBuffer(audioBuffer []float32, audioLength time.Duration)
 	})
 }
 
+// StartTime of the voice processing. The start and end of all text segments are relative to it.
+func (v *Voice) StartTime() time.Time {
+	return v.voiceStart
+}
+
 // Process processes given Discord audio data. Returns ErrVoiceClosed if Close() has been called already.
 func (v *Voice) Process(data []byte) error {
 	if v.closed {
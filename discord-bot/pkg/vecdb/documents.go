@@ -0,0 +1,164 @@
+package vecdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+// documentRequestLimit is the maximum amount of chunks that will be read when listing the stored documents.
+const documentRequestLimit = 10000
+
+// ContentHash of a document as stored by StoreDocument.
+func ContentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// StoreDocument in the given collection. All chunks that were stored for the same source before will be replaced.
+// The content hash of the text is stored alongside, so DocumentHashes can tell if the document changed.
+func (c *Client) StoreDocument(collectionName, source, text string) error {
+	collectionExists, err := c.collectionExists(collectionName)
+	if err != nil {
+		return err
+	}
+	if collectionExists {
+		if err := c.DeleteDocument(collectionName, source); err != nil {
+			return err
+		}
+	} else {
+		err = c.createCollection(collectionName, &models.Property{
+			Name:     string(Chunk),
+			DataType: []string{"text"},
+		}, &models.Property{
+			Name:     string(ChunkIndex),
+			DataType: []string{"int"},
+		}, &models.Property{
+			Name:     string(Source),
+			DataType: []string{"text"},
+		}, &models.Property{
+			Name:     string(Hash),
+			DataType: []string{"text"},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	hash := ContentHash(text)
+	chunks := chunkText(text)
+	propertyObjs := make([]*models.Object, len(chunks))
+	for i, chunk := range chunks {
+		propertyObjs[i] = &models.Object{
+			Class: collectionName,
+			Properties: map[string]any{
+				string(Chunk):      chunk,
+				string(ChunkIndex): i,
+				string(Source):     source,
+				string(Hash):       hash,
+			},
+		}
+	}
+	_, err = c.wc.Batch().ObjectsBatcher().WithObjects(propertyObjs...).Do(context.Background())
+	return err
+}
+
+// DeleteDocument removes all chunks of the source from the collection.
+func (c *Client) DeleteDocument(collectionName, source string) error {
+	_, err := c.wc.Batch().ObjectsBatchDeleter().
+		WithClassName(collectionName).
+		WithWhere(filters.Where().
+			WithPath([]string{string(Source)}).
+			WithOperator(filters.Equal).
+			WithValueText(source)).
+		Do(context.Background())
+	return err
+}
+
+// DocumentHashes of all documents stored in the collection by their source. Returns an empty map if the collection doesn't exist yet.
+func (c *Client) DocumentHashes(collectionName string) (map[string]string, error) {
+	hashes := make(map[string]string)
+	collectionExists, err := c.collectionExists(collectionName)
+	if err != nil || !collectionExists {
+		return hashes, err
+	}
+	res, err := c.wc.GraphQL().Get().
+		WithClassName(collectionName).
+		WithFields(graphql.Field{Name: string(Source)}, graphql.Field{Name: string(Hash)}).
+		WithLimit(documentRequestLimit).
+		Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Errors) > 0 {
+		for _, e := range res.Errors {
+			err = errors.Join(err, errors.New(e.Message))
+		}
+		return nil, err
+	}
+
+	get := res.Data["Get"].(map[string]interface{})
+	col := get[collectionName].([]interface{})
+	for _, data := range col {
+		mapData := data.(map[string]interface{})
+		source, _ := mapData[string(Source)].(string)
+		hash, _ := mapData[string(Hash)].(string)
+		hashes[source] = hash
+	}
+	return hashes, nil
+}
+
+// ReadDocument from the collection as it was stored by StoreDocument without surrounding whitespace.
+// Returns an empty string if the source doesn't exist.
+func (c *Client) ReadDocument(collectionName, source string) (string, error) {
+	res, err := c.wc.GraphQL().Get().
+		WithClassName(collectionName).
+		WithFields(graphql.Field{Name: string(Chunk)}).
+		WithWhere(filters.Where().
+			WithPath([]string{string(Source)}).
+			WithOperator(filters.Equal).
+			WithValueText(source)).
+		WithSort(graphql.Sort{Path: []string{string(ChunkIndex)}, Order: graphql.Asc}).
+		WithLimit(documentRequestLimit).
+		Do(context.Background())
+	if err != nil {
+		return "", err
+	}
+	if len(res.Errors) > 0 {
+		for _, e := range res.Errors {
+			err = errors.Join(err, errors.New(e.Message))
+		}
+		return "", err
+	}
+
+	get := res.Data["Get"].(map[string]interface{})
+	col := get[collectionName].([]interface{})
+	chunks := make([]string, len(col))
+	for i, data := range col {
+		chunks[i] = data.(map[string]interface{})[string(Chunk)].(string)
+	}
+	return joinChunks(chunks), nil
+}
+
+// joinChunks created by chunkText back into the text.
+func joinChunks(chunks []string) string {
+	var sb strings.Builder
+	for _, chunk := range chunks {
+		// Every chunk starts with a line that is repeated for context
+		_, text, _ := strings.Cut(chunk, "\n")
+		// chunkText joins all words with a space, including the first word of a line with the line break before it
+		sb.WriteString(strings.ReplaceAll(text, "\n ", "\n"))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// SearchDocuments of the specified collection against the search concepts. Returns a limited amount of stored chunk data ordered by relevance.
+func (c *Client) SearchDocuments(collectionName string, searchConcepts ...string) ([]string, error) {
+	return c.SearchTranscripts(collectionName, searchConcepts...)
+}
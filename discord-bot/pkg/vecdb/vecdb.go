@@ -9,6 +9,7 @@ import (
 
 	"github.com/weaviate/weaviate-go-client/v4/weaviate"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate/fault"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
 	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
 	"github.com/weaviate/weaviate/entities/models"
 )
@@ -21,6 +22,12 @@ const (
 	Chunk ChunkProperty = "chunk"
 	// ChunkIndex within this collection.
 	ChunkIndex ChunkProperty = "chunk_index"
+	// Source document of the chunk. Only set for documents.
+	Source ChunkProperty = "source"
+	// Hash of the whole source document. Only set for documents.
+	Hash ChunkProperty = "content_hash"
+	// Session the chunk was recorded in. Only set for texts.
+	Session ChunkProperty = "session"
 )
 
 const (
@@ -61,8 +68,58 @@ func NewClient(scheme, addr string) (*Client, error) {
 	return &Client{wc: *wc}, wc.WaitForWeavaite(clientStartupTimeout)
 }
 
-// StoreText in the vector db. The text will automatically be chunked.
-func (c *Client) StoreText(collectionName, text string) error {
+// StoreText of the given session in the vector db. The text will automatically be chunked and appended after the
+// chunks that are already stored in the collection.
+func (c *Client) StoreText(collectionName, session, text string) error {
+	chunks := chunkText(text)
+
+	nextIndex := 0
+	collectionExists, err := c.collectionExists(collectionName)
+	if err != nil {
+		return err
+	}
+	if collectionExists {
+		highestCurrentIndex, err := c.HighestChunkIndex(collectionName)
+		if err != nil {
+			return err
+		}
+		nextIndex = highestCurrentIndex + 1
+	} else {
+		err = c.createCollection(collectionName, &models.Property{
+			Name:     string(Chunk),
+			DataType: []string{"text"},
+		}, &models.Property{
+			Name:     string(ChunkIndex),
+			DataType: []string{"int"},
+		}, &models.Property{
+			Name:     string(Session),
+			DataType: []string{"text"},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	batchJob := c.wc.Batch().ObjectsBatcher()
+
+	propertyObjs := make([]*models.Object, len(chunks))
+	for i, chunk := range chunks {
+		propertyObjs[i] = &models.Object{
+			Class: collectionName,
+			Properties: map[string]any{
+				string(Chunk):      chunk,
+				string(ChunkIndex): i + nextIndex,
+				string(Session):    session,
+			},
+		}
+	}
+
+	_, err = batchJob.WithObjects(propertyObjs...).Do(context.Background())
+	return err
+}
+
+// chunkText into chunks of about textChunkSize words. Each chunk starts with the last line of the previous chunk for context.
+func chunkText(text string) []string {
 	trimmedText := strings.TrimSpace(text)
 	lines := strings.Split(trimmedText, "\n")
 	allWordCount := len(strings.Split(trimmedText, " "))
@@ -83,61 +140,32 @@ func (c *Client) StoreText(collectionName, text string) error {
 	if len(currentChunk) > 0 {
 		chunks = append(chunks, lastLine+strings.Join(currentChunk, " "))
 	}
+	return chunks
+}
 
-	highestCurrentIndex := 0
-	collectionExists := false
+func (c *Client) collectionExists(collectionName string) (bool, error) {
 	_, err := c.wc.Schema().ClassGetter().WithClassName(collectionName).Do(context.Background())
 	if err == nil {
-		collectionExists = true
-		highestCurrentIndex, err = c.HighestChunkIndex(collectionName)
-		if err != nil {
-			return err
-		}
-	} else if weaveErr, ok := err.(*fault.WeaviateClientError); !ok || weaveErr.StatusCode != 404 {
-		// Unexpected or not 404 error means something went terribly wrong
-		return weaveErr
+		return true, nil
 	}
-
-	if !collectionExists {
-		err = c.wc.Schema().ClassCreator().WithClass(&models.Class{
-			Class: collectionName,
-			Properties: []*models.Property{
-				{
-					Name:     string(Chunk),
-					DataType: []string{"text"},
-				},
-				{
-					Name:     string(ChunkIndex),
-					DataType: []string{"int"},
-				},
-			},
-			Vectorizer: "text2vec-openai",
-			ModuleConfig: map[string]any{
-				"generative-openai": map[string]any{
-					"model": "gpt-4",
-				},
-			},
-		}).Do(context.Background())
-		if err != nil {
-			return err
-		}
+	if weaveErr, ok := err.(*fault.WeaviateClientError); ok && weaveErr.StatusCode == 404 {
+		return false, nil
 	}
+	// Unexpected or not 404 error means something went terribly wrong
+	return false, err
+}
 
-	batchJob := c.wc.Batch().ObjectsBatcher()
-
-	propertyObjs := make([]*models.Object, len(chunks))
-	for i, chunk := range chunks {
-		propertyObjs[i] = &models.Object{
-			Class: collectionName,
-			Properties: map[string]any{
-				string(Chunk):      chunk,
-				string(ChunkIndex): i + highestCurrentIndex,
+func (c *Client) createCollection(collectionName string, properties ...*models.Property) error {
+	return c.wc.Schema().ClassCreator().WithClass(&models.Class{
+		Class:      collectionName,
+		Properties: properties,
+		Vectorizer: "text2vec-openai",
+		ModuleConfig: map[string]any{
+			"generative-openai": map[string]any{
+				"model": "gpt-4",
 			},
-		}
-	}
-
-	_, err = batchJob.WithObjects(propertyObjs...).Do(context.Background())
-	return err
+		},
+	}).Do(context.Background())
 }
 
 // HighestChunkIndex for given collection.
@@ -197,6 +225,76 @@ func (c *Client) PromptText(collectionName, prompt string, searchConcepts ...str
 	return groupedResult, nil
 }
 
+// LatestChunks of the last session stored by StoreText in the order they were stored. Returns at most limit chunks
+// and none if the collection doesn't exist yet. Chunks that were stored without a session are not filtered.
+func (c *Client) LatestChunks(collectionName string, limit int) ([]string, error) {
+	collectionExists, err := c.collectionExists(collectionName)
+	if err != nil || !collectionExists {
+		return nil, err
+	}
+	session, err := c.latestSession(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	query := c.wc.GraphQL().Get().
+		WithClassName(collectionName).
+		WithFields(graphql.Field{Name: string(Chunk)}).
+		WithSort(graphql.Sort{Path: []string{string(ChunkIndex)}, Order: graphql.Desc}).
+		WithLimit(limit)
+	if session != "" {
+		query = query.WithWhere(filters.Where().
+			WithPath([]string{string(Session)}).
+			WithOperator(filters.Equal).
+			WithValueText(session))
+	}
+	res, err := query.Do(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Errors) > 0 {
+		for _, e := range res.Errors {
+			err = errors.Join(err, errors.New(e.Message))
+		}
+		return nil, err
+	}
+
+	get := res.Data["Get"].(map[string]interface{})
+	col := get[collectionName].([]interface{})
+	chunks := make([]string, len(col))
+	for i, data := range col {
+		mapData := data.(map[string]interface{})
+		chunks[len(col)-1-i] = mapData[string(Chunk)].(string)
+	}
+	return chunks, nil
+}
+
+// latestSession of the collection is the session of the chunk with the highest index. Empty if it has none.
+func (c *Client) latestSession(collectionName string) (string, error) {
+	res, err := c.wc.GraphQL().Get().
+		WithClassName(collectionName).
+		WithFields(graphql.Field{Name: string(Session)}).
+		WithSort(graphql.Sort{Path: []string{string(ChunkIndex)}, Order: graphql.Desc}).
+		WithLimit(1).
+		Do(context.Background())
+	if err != nil {
+		return "", err
+	}
+	if len(res.Errors) > 0 {
+		for _, e := range res.Errors {
+			err = errors.Join(err, errors.New(e.Message))
+		}
+		return "", err
+	}
+
+	get := res.Data["Get"].(map[string]interface{})
+	col := get[collectionName].([]interface{})
+	if len(col) == 0 {
+		return "", nil
+	}
+	session, _ := col[0].(map[string]any)[string(Session)].(string)
+	return session, nil
+}
+
 // SearchTranscripts for specified collection agains the search concepts. Returns a limited amount of stored chunk data.
 func (c *Client) SearchTranscripts(collectionName string, searchConcepts ...string) ([]string, error) {
 	res, err := c.wc.GraphQL().Get().
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	_ "embed"
@@ -41,7 +42,7 @@ func TestStoreAndRetrieve(t *testing.T) {
 		t.Fatalf("unexpected error while operating weaviate: %v", err)
 	}
 
-	err = DefaultClient().StoreText(colName, exampleTranscript)
+	err = DefaultClient().StoreText(colName, "2024-06-01T19:00:00Z", exampleTranscript)
 	if err != nil {
 		t.Fatalf("could not store text data: %v", err)
 	}
@@ -61,3 +62,87 @@ func TestStoreAndRetrieve(t *testing.T) {
 	}
 	fmt.Printf("got resulting prompt:\n%s\n", result)
 }
+
+func TestStoreDocument(t *testing.T) {
+	colName := "TestLore"
+
+	DefaultClient().wc.Schema().ClassDeleter().WithClassName(colName).Do(context.Background())
+
+	if err := DefaultClient().StoreDocument(colName, "places.md", exampleTranscript); err != nil {
+		t.Fatalf("could not store document: %v", err)
+	}
+	if err := DefaultClient().StoreDocument(colName, "places.md", "# Places\nThe Sun Tower is the tallest building in town."); err != nil {
+		t.Fatalf("could not replace document: %v", err)
+	}
+
+	hashes, err := DefaultClient().DocumentHashes(colName)
+	if err != nil {
+		t.Fatalf("could not get document hashes: %v", err)
+	}
+	if len(hashes) != 1 || hashes["places.md"] != ContentHash("# Places\nThe Sun Tower is the tallest building in town.") {
+		t.Errorf("expected only the hash of the replaced document but got %v", hashes)
+	}
+
+	lore, err := DefaultClient().SearchDocuments(colName, "Sun Tower")
+	if err != nil {
+		t.Fatalf("could not search documents: %v", err)
+	}
+	if len(lore) != 1 {
+		t.Errorf("expected the old chunks to be replaced but got %d chunks", len(lore))
+	}
+}
+
+func TestLatestChunks(t *testing.T) {
+	colName := "TestLatest"
+
+	DefaultClient().wc.Schema().ClassDeleter().WithClassName(colName).Do(context.Background())
+
+	if err := DefaultClient().StoreText(colName, "2024-06-01T19:00:00Z", exampleTranscript); err != nil {
+		t.Fatalf("could not store text data: %v", err)
+	}
+	latest, err := DefaultClient().LatestChunks(colName, 2)
+	if err != nil {
+		t.Fatalf("could not get latest chunks: %v", err)
+	}
+	chunks := chunkText(exampleTranscript)
+	if len(latest) != 2 || latest[1] != chunks[len(chunks)-1] {
+		t.Errorf("expected the last two chunks in order but got %q", latest)
+	}
+
+	// A second session continues after the chunks of the first one
+	if err := DefaultClient().StoreText(colName, "2024-06-08T19:00:00Z", "Amon: Wo waren wir stehen geblieben?"); err != nil {
+		t.Fatalf("could not store text data: %v", err)
+	}
+	if index, err := DefaultClient().HighestChunkIndex(colName); err != nil || index != len(chunks) {
+		t.Errorf("expected highest index %d but got %d: %v", len(chunks), index, err)
+	}
+	latest, err = DefaultClient().LatestChunks(colName, 2)
+	if err != nil {
+		t.Fatalf("could not get latest chunks: %v", err)
+	}
+	if len(latest) != 1 || !strings.Contains(latest[0], "Wo waren wir stehen geblieben?") {
+		t.Errorf("expected only the chunk of the last session but got %q", latest)
+	}
+}
+
+func TestReadDocument(t *testing.T) {
+	colName := "TestReadLore"
+
+	DefaultClient().wc.Schema().ClassDeleter().WithClassName(colName).Do(context.Background())
+
+	for source, text := range map[string]string{
+		"places.md":      "# Places\nThe Sun Tower is the tallest building in town.\n\n  - indented\n",
+		"transcript.txt": exampleTranscript,
+	} {
+		if err := DefaultClient().StoreDocument(colName, source, text); err != nil {
+			t.Fatalf("could not store document: %v", err)
+		}
+		read, err := DefaultClient().ReadDocument(colName, source)
+		if err != nil {
+			t.Fatalf("could not read document: %v", err)
+		}
+		if read != strings.TrimSpace(text) {
+			t.Errorf("expected document %s to be read as it was stored but got %q", source, read)
+		}
+	}
+}